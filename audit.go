@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appleboy/com/gh"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Kinds of audited requests
+const (
+	auditKindCompletion      = "completion"
+	auditKindJudgeEvaluation = "judge_evaluation"
+	auditKindJudgeSelection  = "judge_selection"
+	auditKindBatch           = "batch"
+)
+
+// auditGenesisHash is the previous hash of the first entry of an audit file
+var auditGenesisHash = strings.Repeat("0", sha256.Size*2)
+
+var errAuditFileRequired = errors.New("verify-audit: an audit file is required (argument or INPUT_AUDIT_FILE)")
+
+// activeAuditor records the requests of the current run, or is nil when audit_file is not set
+var activeAuditor *auditor
+
+// auditor appends every chat completion request and its response to an audit file.
+// Each entry holds the SHA-256 hash of the previous one, so editing, removing or
+// reordering entries breaks the chain.
+type auditor struct {
+	mu       sync.Mutex
+	file     string
+	sequence int
+	lastHash string
+}
+
+// auditEntry is a line of the audit file. Hash must stay the last field: it is the
+// SHA-256 of the entry encoded without it.
+type auditEntry struct {
+	Sequence   int                            `json:"seq"`
+	Timestamp  string                         `json:"timestamp"`
+	Kind       string                         `json:"kind"`
+	Repository string                         `json:"repository,omitempty"`
+	RunID      string                         `json:"run_id,omitempty"`
+	BaseURL    string                         `json:"base_url"`
+	APIKey     string                         `json:"api_key"`
+	Request    openai.ChatCompletionRequest   `json:"request"`
+	Response   *openai.ChatCompletionResponse `json:"response,omitempty"`
+	Error      string                         `json:"error,omitempty"`
+	PrevHash   string                         `json:"prev_hash"`
+	Hash       string                         `json:"hash,omitempty"`
+}
+
+// newAuditor creates an auditor from the audit_file input, continuing the chain of an
+// existing file. It returns nil when no audit file is configured.
+func newAuditor() (*auditor, error) {
+	file := strings.TrimSpace(os.Getenv("INPUT_AUDIT_FILE"))
+	if file == "" {
+		return nil, nil
+	}
+
+	a := &auditor{file: file, lastHash: auditGenesisHash}
+	entries, err := readAuditFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("invalid audit_file: %w", err)
+	}
+	if n := len(entries); n > 0 {
+		a.sequence = entries[n-1].Sequence
+		a.lastHash = entries[n-1].Hash
+	}
+	return a, nil
+}
+
+// startAudit enables the audit log when audit_file is set. The returned function
+// reports the last hash of the chain and sets it as the audit_hash output.
+func startAudit() (func(), error) {
+	a, err := newAuditor()
+	if err != nil || a == nil {
+		return func() {}, err
+	}
+
+	activeAuditor = a
+	return func() {
+		activeAuditor = nil
+		if a.sequence == 0 {
+			return
+		}
+		logInfo("audit", fmt.Sprintf("Audit log %s: %d entries, last hash %s", a.file, a.sequence, a.lastHash),
+			"file", a.file, "entries", a.sequence, "hash", a.lastHash)
+		if os.Getenv("GITHUB_OUTPUT") == "" {
+			return
+		}
+		if err := gh.SetOutput(map[string]string{"audit_hash": a.lastHash}); err != nil {
+			warnf("failed to set audit_hash output: %v", err)
+		}
+	}, nil
+}
+
+// auditCompletion records a chat completion request with its response or error
+func auditCompletion(
+	config *Config,
+	kind string,
+	req openai.ChatCompletionRequest,
+	resp openai.ChatCompletionResponse,
+	err error,
+) error {
+	a := activeAuditor
+	if a == nil {
+		return nil
+	}
+
+	apiKey, baseURL := config.APIKey, config.BaseURL
+	if kind == auditKindJudgeEvaluation || kind == auditKindJudgeSelection {
+		judge := config.judgeConfig()
+		apiKey, baseURL = judge.APIKey, judge.BaseURL
+	}
+
+	entry := auditEntry{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
+		Kind:       kind,
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+		RunID:      os.Getenv("GITHUB_RUN_ID"),
+		BaseURL:    baseURL,
+		APIKey:     maskAPIKey(apiKey),
+		Request:    req,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Response = &resp
+	}
+
+	if err := a.append(entry, config.APIKey, config.JudgeAPIKey); err != nil {
+		return fmt.Errorf("failed to write audit_file: %w", err)
+	}
+	return nil
+}
+
+// append chains the entry to the previous one and writes it with every secret masked
+func (a *auditor) append(entry auditEntry, secrets ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry.Sequence = a.sequence + 1
+	entry.PrevHash = a.lastHash
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// Requests and responses never carry the key, unless the model echoes it back.
+	// Short values are skipped as they could match unrelated JSON.
+	for _, secret := range secrets {
+		if len(secret) < 8 {
+			continue
+		}
+		encoded, _ := json.Marshal(secret)
+		data = bytes.ReplaceAll(data, bytes.Trim(encoded, `"`), []byte(maskAPIKey(secret)))
+	}
+
+	hash := auditHash(data)
+	line := append(data[:len(data)-1], fmt.Sprintf(`,"hash":"%s"}`+"\n", hash)...)
+
+	f, err := os.OpenFile(a.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	a.sequence = entry.Sequence
+	a.lastHash = hash
+	return nil
+}
+
+// auditHash returns the hex SHA-256 of an entry encoded without its hash
+func auditHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readAuditFile reads and verifies the hash chain of an audit file
+func readAuditFile(path string) ([]auditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []auditEntry
+	prevHash := auditGenesisHash
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		var entry auditEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		suffix := fmt.Sprintf(`,"hash":"%s"}`, entry.Hash)
+		if entry.Hash == "" || !bytes.HasSuffix(data, []byte(suffix)) {
+			return nil, fmt.Errorf("line %d: missing hash", line)
+		}
+		unhashed := append(bytes.Clone(data[:len(data)-len(suffix)]), '}')
+		if got := auditHash(unhashed); got != entry.Hash {
+			return nil, fmt.Errorf("line %d: hash mismatch, the entry was modified", line)
+		}
+		if entry.PrevHash != prevHash {
+			return nil, fmt.Errorf("line %d: previous hash mismatch, an entry was removed or reordered", line)
+		}
+		if entry.Sequence != len(entries)+1 {
+			return nil, fmt.Errorf("line %d: sequence %d, want %d", line, entry.Sequence, len(entries)+1)
+		}
+
+		entries = append(entries, entry)
+		prevHash = entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// runVerifyAudit implements the verify-audit subcommand
+func runVerifyAudit(args []string) error {
+	fs := flag.NewFlagSet("verify-audit", flag.ContinueOnError)
+	expected := fs.String("hash", "", "expected hash of the last entry, such as a recorded audit_hash output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		if file := strings.TrimSpace(os.Getenv("INPUT_AUDIT_FILE")); file != "" {
+			files = []string{file}
+		}
+	}
+	if len(files) == 0 {
+		return errAuditFileRequired
+	}
+
+	for _, file := range files {
+		entries, err := readAuditFile(file)
+		if err != nil {
+			return fmt.Errorf("verify-audit: %s: %w", file, err)
+		}
+
+		lastHash := auditGenesisHash
+		if len(entries) > 0 {
+			lastHash = entries[len(entries)-1].Hash
+		}
+		if *expected != "" && lastHash != *expected {
+			return fmt.Errorf("verify-audit: %s: last hash %s, want %s (entries were removed)", file, lastHash, *expected)
+		}
+		logInfo("audit_verified", fmt.Sprintf("%s: %d entries verified, last hash %s", file, len(entries), lastHash),
+			"file", file, "entries", len(entries), "hash", lastHash)
+	}
+	return nil
+}
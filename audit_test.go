@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runAudited runs the action against the echo server with an audit file
+func runAudited(t *testing.T, auditFile string) string {
+	t.Helper()
+
+	server := newEchoServer(t, nil, nil)
+	defer server.Close()
+
+	outputFile := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	t.Setenv("INPUT_BASE_URL", server.URL)
+	t.Setenv("INPUT_API_KEY", "sk-audit-1234567890")
+	t.Setenv("INPUT_MODEL", "gpt-4o")
+	t.Setenv("INPUT_INPUT_PROMPT", "Hello sk-audit-1234567890")
+	t.Setenv("INPUT_AUDIT_FILE", auditFile)
+	// Let the key through to check that it is masked when the model echoes it
+	t.Setenv("INPUT_SECRET_SCAN", "false")
+
+	if err := run(); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+	if activeAuditor != nil {
+		t.Error("expected auditing to be disabled after the run")
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read outputs: %v", err)
+	}
+	return string(content)
+}
+
+func TestRunWithAudit(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	runAudited(t, auditFile)
+	output := runAudited(t, auditFile)
+
+	entries, err := readAuditFile(auditFile)
+	if err != nil {
+		t.Fatalf("readAuditFile() unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit file has %d entries, want one per run", len(entries))
+	}
+	if entries[0].PrevHash != auditGenesisHash || entries[1].PrevHash != entries[0].Hash {
+		t.Errorf("entries are not chained: %+v", entries)
+	}
+	if !strings.Contains(output, "audit_hash="+entries[1].Hash) {
+		t.Errorf("outputs = %q, want the last hash as audit_hash", output)
+	}
+
+	entry := entries[1]
+	if entry.Kind != auditKindCompletion || entry.APIKey != "sk-a********7890" || entry.Request.Model != "gpt-4o" ||
+		entry.Response == nil || entry.Response.Choices[0].Message.Content != "gpt-4o: Hello sk-a********7890" {
+		t.Errorf("audit entry = %+v", entry)
+	}
+
+	content, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "sk-audit-1234567890") {
+		t.Error("expected the API key to be masked in the audit file")
+	}
+}
+
+func TestReadAuditFileTampering(t *testing.T) {
+	dir := t.TempDir()
+	auditFile := filepath.Join(dir, "audit.jsonl")
+	t.Setenv("INPUT_AUDIT_FILE", auditFile)
+	a, err := newAuditor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []string{"gpt-4o", "gpt-4o-mini", "o3"} {
+		entry := auditEntry{Kind: auditKindCompletion}
+		entry.Request.Model = model
+		if err := a.append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSpace(string(content)), "\n")
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"intact", string(content), ""},
+		{"modified", strings.Replace(string(content), "gpt-4o-mini", "gpt-4o-nano", 1), "hash mismatch"},
+		{"removed", lines[0] + lines[2], "previous hash mismatch"},
+		{"reordered", lines[1] + lines[0] + lines[2], "previous hash mismatch"},
+		{"no hash", `{"seq":1}`, "missing hash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := readAuditFile(path)
+			if tt.wantErr == "" {
+				if err != nil || len(entries) != 3 {
+					t.Errorf("readAuditFile() = %d entries, %v, want 3 entries", len(entries), err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readAuditFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A tampered file is not extended
+	t.Setenv("INPUT_AUDIT_FILE", filepath.Join(dir, "modified.jsonl"))
+	if _, err := newAuditor(); err == nil || !strings.Contains(err.Error(), "invalid audit_file") {
+		t.Errorf("newAuditor() error = %v, want invalid audit_file", err)
+	}
+}
+
+func TestRunVerifyAudit(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("INPUT_AUDIT_FILE", auditFile)
+	a, err := newAuditor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.append(auditEntry{Kind: auditKindCompletion}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     string
+		args    []string
+		wantErr string
+	}{
+		{name: "file argument", args: []string{auditFile}},
+		{name: "audit_file input", env: auditFile},
+		{name: "expected hash", args: []string{"-hash", a.lastHash, auditFile}},
+		{name: "truncated", args: []string{"-hash", strings.Repeat("f", 64), auditFile}, wantErr: "entries were removed"},
+		{name: "missing file", args: []string{"missing.jsonl"}, wantErr: "verify-audit: missing.jsonl"},
+		{name: "no file", wantErr: errAuditFileRequired.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_AUDIT_FILE", tt.env)
+			err := execute(append([]string{"verify-audit"}, tt.args...))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("verify-audit unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verify-audit error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
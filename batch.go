@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appleboy/com/gh"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// BatchItem is a single input of a batch run
+type BatchItem struct {
+	Index int
+	// Source is the file path or dataset line the item was read from
+	Source string
+	// Data is exposed to the input prompt template as {{.item}}
+	Data any
+	// Context replaces the context appended to the user prompt when set
+	Context string
+}
+
+// BatchResult is the outcome of processing one batch item
+type BatchResult struct {
+	Index    int          `json:"index"`
+	Source   string       `json:"source"`
+	Response string       `json:"response,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	File     string       `json:"file,omitempty"`
+	Usage    openai.Usage `json:"usage"`
+	// Redactions is the number of values replaced by placeholders in the prompt
+	Redactions int `json:"redactions,omitempty"`
+}
+
+// loadBatchItems loads batch items from a JSONL source (ending in .jsonl) or from
+// one or more newline separated glob patterns
+func loadBatchItems(input string) ([]BatchItem, error) {
+	source, _ := splitIntegrity(input)
+	if strings.HasSuffix(strings.ToLower(source), ".jsonl") {
+		return loadBatchItemsFromJSONL(input)
+	}
+	return loadBatchItemsFromGlob(input)
+}
+
+// loadBatchItemsFromJSONL loads one item per non-empty JSONL line
+func loadBatchItemsFromJSONL(input string) ([]BatchItem, error) {
+	content, err := LoadContent(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load batch_inputs: %w", err)
+	}
+
+	var items []BatchItem
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var data any
+		if err := json.Unmarshal([]byte(line), &data); err != nil {
+			return nil, fmt.Errorf("invalid batch_inputs JSON on line %d: %w", i+1, err)
+		}
+		items = append(items, BatchItem{
+			Index:  len(items),
+			Source: fmt.Sprintf("%s:%d", input, i+1),
+			Data:   data,
+		})
+	}
+
+	return items, nil
+}
+
+// loadBatchItemsFromGlob loads one item per file matching any of the glob patterns.
+// Each item exposes the file path, name and content to the template.
+func loadBatchItemsFromGlob(input string) ([]BatchItem, error) {
+	var items []BatchItem
+	seen := make(map[string]bool)
+
+	for _, pattern := range strings.Split(input, "\n") {
+		matches, err := globFiles(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range matches {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read batch input %s: %w", path, err)
+			}
+			items = append(items, BatchItem{
+				Index:  len(items),
+				Source: path,
+				Data: map[string]any{
+					"path":    filepath.ToSlash(path),
+					"name":    filepath.Base(path),
+					"content": string(content),
+				},
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// rateLimiter spaces out requests shared by all workers.
+// A nil limiter does not limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter creates a limiter allowing perMinute requests per minute, or nil when perMinute is 0
+func newRateLimiter(perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until the next request is allowed or the context is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// batchRunner processes batch items with shared clients, tool schema and rate limiter
+type batchRunner struct {
+	client      *openai.Client
+	judgeClient *openai.Client
+	config      *Config
+	toolMeta    *ToolMeta
+	limiter     *rateLimiter
+}
+
+// process renders the input prompt for a single item and sends it to the LLM.
+// Failures are recorded in the result instead of being returned.
+func (r *batchRunner) process(ctx context.Context, item BatchItem) BatchResult {
+	result := BatchResult{Index: item.Index, Source: item.Source}
+
+	itemConfig, messages, redactor, err := buildBatchItemMessages(r.config, item)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Redactions = redactor.Count()
+
+	if err := r.limiter.Wait(ctx); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	completion, err := complete(ctx, r.client, r.judgeClient, itemConfig, messages, r.toolMeta)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Response = completion.Response()
+	if r.config.RedactRestore {
+		result.Response = redactor.restore(result.Response)
+	}
+	result.Usage = completion.Usage
+
+	writeBatchResultFile(r.config, r.toolMeta, &result)
+	return result
+}
+
+// buildBatchItemMessages renders the input prompt for a single item and builds its messages,
+// redacting them with a redactor of their own when redaction is enabled
+func buildBatchItemMessages(
+	config *Config,
+	item BatchItem,
+) (*Config, []openai.ChatCompletionMessage, *redactor, error) {
+	prompt := config.InputPrompt
+	if config.templateEnabled("input_prompt") {
+		rendered, err := RenderTemplateWithData(config.InputPrompt, map[string]any{
+			"item":  item.Data,
+			"index": item.Index,
+		}, config.TemplateStrict)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to render input_prompt: %w", err)
+		}
+		prompt = rendered
+	}
+
+	itemConfig := *config
+	itemConfig.InputPrompt = prompt
+	if item.Context != "" {
+		itemConfig.Context = item.Context
+	}
+	redactor := newRedactor(&itemConfig)
+	return &itemConfig, redactor.redactMessages(BuildMessages(&itemConfig)), redactor, nil
+}
+
+// writeBatchResultFile writes a successful result to batch_output_dir when configured
+func writeBatchResultFile(config *Config, toolMeta *ToolMeta, result *BatchResult) {
+	if config.BatchOutputDir == "" || result.Error != "" {
+		return
+	}
+
+	ext := ".txt"
+	if toolMeta != nil {
+		ext = ".json"
+	}
+	path := filepath.Join(config.BatchOutputDir, fmt.Sprintf("%04d%s", result.Index, ext))
+	if err := os.WriteFile(path, []byte(result.Response), 0o600); err != nil {
+		result.Error = fmt.Sprintf("failed to write result file: %v", err)
+		return
+	}
+	result.File = path
+}
+
+// runBatchItems processes all items with bounded concurrency, keeping results in item order
+func runBatchItems(ctx context.Context, runner *batchRunner, items []BatchItem, concurrency int) []BatchResult {
+	results := make([]BatchResult, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runner.process(ctx, item)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runBatch runs the input prompt over every batch item and sets the aggregated outputs.
+// The step only fails when every item failed.
+func runBatch(
+	ctx context.Context,
+	config *Config,
+	client *openai.Client,
+	judgeClient *openai.Client,
+	toolMeta *ToolMeta,
+) error {
+	items, err := prepareBatch(config)
+	if err != nil {
+		return err
+	}
+
+	// Submit through the asynchronous Batch API instead of sending live requests
+	if config.BatchAPI {
+		batch, results, err := runBatchAPI(ctx, config, client, judgeClient, toolMeta, items)
+		if results == nil {
+			return err
+		}
+		// Report the batch even when it did not complete, so that a job that is still
+		// running can be found and partial results are kept
+		extra := map[string]string{"batch_id": batch.ID, "batch_status": batch.Status}
+		if finishErr := finishBatch(config, results, extra); err == nil {
+			err = finishErr
+		}
+		return err
+	}
+
+	logInfo("batch_started",
+		fmt.Sprintf("Processing %d batch items (concurrency: %d)...", len(items), config.BatchConcurrency),
+		"items", len(items), "concurrency", config.BatchConcurrency)
+
+	runner := &batchRunner{
+		client:      client,
+		judgeClient: judgeClient,
+		config:      config,
+		toolMeta:    toolMeta,
+		limiter:     newRateLimiter(config.BatchRateLimit),
+	}
+	results := runBatchItems(ctx, runner, items, config.BatchConcurrency)
+
+	return finishBatch(config, results, nil)
+}
+
+// prepareBatch loads the batch items and creates the output directory
+func prepareBatch(config *Config) ([]BatchItem, error) {
+	items, err := loadBatchItems(config.BatchInputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("batch_inputs did not match any items")
+	}
+
+	if config.BatchOutputDir != "" {
+		if err := os.MkdirAll(config.BatchOutputDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create batch_output_dir: %w", err)
+		}
+	}
+
+	return items, nil
+}
+
+// finishBatch reports the batch results, sets the aggregated outputs and fails
+// only when every item failed. Extra outputs are added as-is.
+func finishBatch(config *Config, results []BatchResult, extra map[string]string) error {
+	var usage openai.Usage
+	failed := 0
+	redactions := 0
+	for _, r := range results {
+		usage = sumUsage(usage, r.Usage)
+		redactions += r.Redactions
+		if r.Error != "" {
+			failed++
+			warnf("batch item %d (%s) failed: %s", r.Index, r.Source, r.Error)
+			continue
+		}
+		logInfo("batch_item_completed", fmt.Sprintf("Batch item %d (%s) completed", r.Index, r.Source),
+			"index", r.Index, "source", r.Source)
+	}
+
+	printTokenUsage(usage)
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch results: %w", err)
+	}
+
+	output := map[string]string{
+		"batch_results":   string(data),
+		"batch_succeeded": fmt.Sprint(len(results) - failed),
+		"batch_failed":    fmt.Sprint(failed),
+		"redactions":      fmt.Sprint(redactions),
+	}
+	addTokenUsageToOutput(output, usage)
+	addCostToOutput(output, config, usage)
+	for k, v := range extra {
+		output[k] = v
+	}
+
+	if err := gh.SetOutput(output); err != nil {
+		return fmt.Errorf("failed to set output: %w", err)
+	}
+
+	if failed == len(results) {
+		return fmt.Errorf("all %d batch items failed", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// batchCustomIDPrefix prefixes the item index in Batch API custom IDs
+const batchCustomIDPrefix = "item-"
+
+// batchCompletionWindow is the only completion window supported by the Batch API
+const batchCompletionWindow = "24h"
+
+// Terminal statuses of a Batch API job
+const (
+	batchStatusCompleted = "completed"
+	batchStatusFailed    = "failed"
+	batchStatusExpired   = "expired"
+	batchStatusCancelled = "cancelled"
+)
+
+// batchOutputLine is one line of a Batch API output or error file
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// batchCustomID returns the Batch API custom ID of an item
+func batchCustomID(index int) string {
+	return batchCustomIDPrefix + strconv.Itoa(index)
+}
+
+// parseBatchCustomID returns the item index encoded in a Batch API custom ID
+func parseBatchCustomID(id string) (int, bool) {
+	if !strings.HasPrefix(id, batchCustomIDPrefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(id, batchCustomIDPrefix))
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// isBatchTerminal reports whether a Batch API job will not change status anymore
+func isBatchTerminal(status string) bool {
+	switch status {
+	case batchStatusCompleted, batchStatusFailed, batchStatusExpired, batchStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// runBatchAPI submits one chat completion request per item through the Batch API,
+// waits for the job to finish and maps every result back to its item.
+// It returns the batch together with the per-item results, also when the batch did
+// not complete, so that partial results and the batch ID can still be reported.
+func runBatchAPI(
+	ctx context.Context,
+	config *Config,
+	client *openai.Client,
+	judgeClient *openai.Client,
+	toolMeta *ToolMeta,
+	items []BatchItem,
+) (openai.Batch, []BatchResult, error) {
+	results := make([]BatchResult, len(items))
+	messages := make(map[int][]openai.ChatCompletionMessage, len(items))
+	configs := make(map[int]*Config, len(items))
+	redactors := make(map[int]*redactor, len(items))
+	requests := make(map[int]openai.ChatCompletionRequest, len(items))
+
+	upload := openai.CreateBatchWithUploadFileRequest{
+		Endpoint:         openai.BatchEndpointChatCompletions,
+		CompletionWindow: batchCompletionWindow,
+		Metadata:         map[string]any{"source": ActionName},
+		UploadBatchFileRequest: openai.UploadBatchFileRequest{
+			FileName: "llm-action-batch.jsonl",
+		},
+	}
+	for i, item := range items {
+		results[i] = BatchResult{Index: item.Index, Source: item.Source}
+
+		itemConfig, itemMessages, redactor, err := buildBatchItemMessages(config, item)
+		if err == nil {
+			err = scanMessagesForSecrets(itemConfig, itemMessages)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Redactions = redactor.Count()
+		configs[item.Index] = itemConfig
+		messages[item.Index] = itemMessages
+		redactors[item.Index] = redactor
+		requests[item.Index] = buildChatRequest(itemConfig, itemMessages, toolMeta)
+		upload.AddChatCompletion(batchCustomID(item.Index), requests[item.Index])
+	}
+
+	if len(upload.Lines) == 0 {
+		return openai.Batch{}, results, nil
+	}
+
+	created, err := client.CreateBatchWithUploadFile(ctx, upload)
+	if err != nil {
+		err = fmt.Errorf("failed to create batch: %w", err)
+		markUnanswered(results, configs, nil, err.Error())
+		return openai.Batch{}, results, err
+	}
+	logInfo("batch_submitted", fmt.Sprintf("Submitted batch %s with %d requests", created.ID, len(upload.Lines)),
+		"batch_id", created.ID, "requests", len(upload.Lines))
+
+	batch, err := waitForBatch(ctx, client, created.Batch, config.BatchPollInterval, config.BatchTimeout)
+	if err != nil {
+		markUnanswered(results, configs, nil, fmt.Sprintf("batch %s did not finish (status: %s)", batch.ID, batch.Status))
+		return batch, results, err
+	}
+
+	// Expired, cancelled and failed batches can still have output and error files
+	// for the requests that were processed
+	lines, err := downloadBatchLines(ctx, client, batch.OutputFileID, batch.ErrorFileID)
+	if err != nil {
+		markUnanswered(results, configs, nil, err.Error())
+		return batch, results, err
+	}
+
+	answered := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		index, ok := parseBatchCustomID(line.CustomID)
+		if !ok || index < 0 || index >= len(results) || configs[index] == nil {
+			warnf("ignoring batch result with unknown custom_id %q", line.CustomID)
+			continue
+		}
+		answered[index] = true
+		result := &results[index]
+
+		resp, err := decodeBatchLine(line)
+		if auditErr := auditCompletion(configs[index], auditKindBatch, requests[index], resp, err); auditErr != nil {
+			return batch, nil, auditErr
+		}
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Usage = resp.Usage
+
+		candidates, err := extractCandidates(resp, toolMeta, config.Debug)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		selection, err := selectCandidate(ctx, judgeClient, configs[index], messages[index], candidates, toolMeta != nil)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Usage = sumUsage(result.Usage, selection.Usage)
+		result.Response = candidates[selection.Index]
+		if config.RedactRestore {
+			result.Response = redactors[index].restore(result.Response)
+		}
+
+		writeBatchResultFile(config, toolMeta, result)
+	}
+
+	if batch.Status != batchStatusCompleted {
+		markUnanswered(results, configs, answered, fmt.Sprintf("no result returned by batch (status: %s)", batch.Status))
+		return batch, results, fmt.Errorf(
+			"batch %s finished with status %s%s", batch.ID, batch.Status, batchErrorSummary(batch),
+		)
+	}
+	markUnanswered(results, configs, answered, "no result returned by batch")
+
+	return batch, results, nil
+}
+
+// markUnanswered sets msg as the error of every submitted item without an answer
+func markUnanswered(results []BatchResult, configs map[int]*Config, answered map[int]bool, msg string) {
+	for index := range configs {
+		if !answered[index] {
+			results[index].Error = msg
+		}
+	}
+}
+
+// waitForBatch polls the Batch API until the job reaches a terminal status or the timeout expires
+func waitForBatch(
+	ctx context.Context,
+	client *openai.Client,
+	batch openai.Batch,
+	interval time.Duration,
+	timeout time.Duration,
+) (openai.Batch, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for !isBatchTerminal(batch.Status) {
+		select {
+		case <-ctx.Done():
+			return batch, fmt.Errorf("timed out waiting for batch %s (status: %s)", batch.ID, batch.Status)
+		case <-ticker.C:
+		}
+
+		resp, err := client.RetrieveBatch(ctx, batch.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return batch, fmt.Errorf("timed out waiting for batch %s (status: %s)", batch.ID, batch.Status)
+			}
+			return batch, fmt.Errorf("failed to retrieve batch %s: %w", batch.ID, err)
+		}
+		batch = resp.Batch
+
+		counts := batch.RequestCounts
+		logInfo("batch_status",
+			fmt.Sprintf("Batch %s: %s (%d/%d completed, %d failed)",
+				batch.ID, batch.Status, counts.Completed, counts.Total, counts.Failed),
+			"batch_id", batch.ID, "status", batch.Status,
+			"completed", counts.Completed, "total", counts.Total, "failed", counts.Failed)
+	}
+
+	return batch, nil
+}
+
+// batchErrorSummary returns the first batch level error, if any, for error messages
+func batchErrorSummary(batch openai.Batch) string {
+	if batch.Errors == nil || len(batch.Errors.Data) == 0 {
+		return ""
+	}
+	first := batch.Errors.Data[0]
+	return fmt.Sprintf(": %s: %s", first.Code, first.Message)
+}
+
+// downloadBatchLines downloads and parses the output and error files of a finished batch
+func downloadBatchLines(ctx context.Context, client *openai.Client, fileIDs ...*string) ([]batchOutputLine, error) {
+	var lines []batchOutputLine
+
+	for _, id := range fileIDs {
+		if id == nil || *id == "" {
+			continue
+		}
+
+		content, err := client.GetFileContent(ctx, *id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download batch file %s: %w", *id, err)
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch file %s: %w", *id, err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var line batchOutputLine
+			if err := json.Unmarshal(text, &line); err != nil {
+				return nil, fmt.Errorf("invalid line in batch file %s: %w", *id, err)
+			}
+			lines = append(lines, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read batch file %s: %w", *id, err)
+		}
+	}
+
+	return lines, nil
+}
+
+// decodeBatchLine returns the chat completion response of a batch result line
+func decodeBatchLine(line batchOutputLine) (openai.ChatCompletionResponse, error) {
+	var resp openai.ChatCompletionResponse
+
+	if line.Error != nil {
+		return resp, fmt.Errorf("batch request failed: %s: %s", line.Error.Code, line.Error.Message)
+	}
+	if line.Response == nil {
+		return resp, fmt.Errorf("batch result has no response")
+	}
+	if line.Response.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("batch request failed with status code %d: %s",
+			line.Response.StatusCode, string(line.Response.Body))
+	}
+	if err := json.Unmarshal(line.Response.Body, &resp); err != nil {
+		return resp, fmt.Errorf("failed to parse batch response: %w", err)
+	}
+
+	return resp, nil
+}
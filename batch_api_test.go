@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// fakeBatchAPI emulates the files and batches endpoints of the Batch API
+type fakeBatchAPI struct {
+	mu       sync.Mutex
+	polls    int
+	status   string
+	uploaded []openai.BatchChatCompletionRequest
+	// respond builds the output and error file lines from the uploaded requests
+	respond func(reqs []openai.BatchChatCompletionRequest) (output, errors []string)
+}
+
+func (f *fakeBatchAPI) batch() openai.Batch {
+	status := "in_progress"
+	if f.polls >= 2 {
+		status = f.status
+	}
+	outputID, errorID := "file-output", "file-errors"
+	return openai.Batch{
+		ID:            "batch_123",
+		Status:        status,
+		OutputFileID:  &outputID,
+		ErrorFileID:   &errorID,
+		RequestCounts: openai.BatchRequestCounts{Total: len(f.uploaded)},
+	}
+}
+
+func (f *fakeBatchAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/files":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		for _, line := range strings.Split(string(data), "\n") {
+			var req openai.BatchChatCompletionRequest
+			if err := json.Unmarshal([]byte(line), &req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.uploaded = append(f.uploaded, req)
+		}
+		_ = json.NewEncoder(w).Encode(openai.File{ID: "file-input", Purpose: "batch"})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/batches":
+		_ = json.NewEncoder(w).Encode(f.batch())
+
+	case r.Method == http.MethodGet && r.URL.Path == "/batches/batch_123":
+		f.polls++
+		_ = json.NewEncoder(w).Encode(f.batch())
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/files/"):
+		output, errors := f.respond(f.uploaded)
+		if r.URL.Path == "/files/file-output/content" {
+			fmt.Fprint(w, strings.Join(output, "\n"))
+		} else {
+			fmt.Fprint(w, strings.Join(errors, "\n"))
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// batchSuccessLine builds an output file line answering a request with the given content
+func batchSuccessLine(customID, content string) string {
+	body, _ := json.Marshal(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content}},
+		},
+		Usage: openai.Usage{PromptTokens: 4, CompletionTokens: 6, TotalTokens: 10},
+	})
+	return fmt.Sprintf(`{"custom_id": %q, "response": {"status_code": 200, "body": %s}}`, customID, body)
+}
+
+func TestRunBatchAPI(t *testing.T) {
+	fake := &fakeBatchAPI{
+		status: batchStatusCompleted,
+		respond: func(reqs []openai.BatchChatCompletionRequest) ([]string, []string) {
+			output := []string{}
+			for _, req := range reqs {
+				// item-2 is reported in the error file and item-3 is never answered
+				if req.CustomID == "item-2" || req.CustomID == "item-3" {
+					continue
+				}
+				output = append(output, batchSuccessLine(req.CustomID, "echo: "+req.Body.Messages[0].Content))
+			}
+			errors := []string{
+				`{"custom_id": "item-2", "response": {"status_code": 429, "body": {"error": "rate limited"}}}`,
+			}
+			return output, errors
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	config := &Config{
+		BaseURL:           server.URL,
+		APIKey:            "test-key",
+		Model:             "gpt-4o-mini",
+		InputPrompt:       "Summarize {{.item}}",
+		BatchPollInterval: 10 * time.Millisecond,
+		BatchTimeout:      5 * time.Second,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	items := []BatchItem{
+		{Index: 0, Source: "a", Data: "alpha"},
+		{Index: 1, Source: "b", Data: "beta"},
+		{Index: 2, Source: "c", Data: "gamma"},
+		{Index: 3, Source: "d", Data: "delta"},
+	}
+
+	batch, results, err := runBatchAPI(context.Background(), config, client, client, nil, items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if batch.ID != "batch_123" {
+		t.Errorf("expected batch ID batch_123, got %s", batch.ID)
+	}
+	if len(fake.uploaded) != 4 {
+		t.Fatalf("expected 4 uploaded requests, got %d", len(fake.uploaded))
+	}
+	if fake.uploaded[1].Body.Model != "gpt-4o-mini" || fake.uploaded[1].URL != openai.BatchEndpointChatCompletions {
+		t.Errorf("unexpected uploaded request: %+v", fake.uploaded[1])
+	}
+
+	if results[0].Response != "echo: Summarize alpha" || results[0].Usage.TotalTokens != 10 {
+		t.Errorf("unexpected result 0: %+v", results[0])
+	}
+	if results[1].Response != "echo: Summarize beta" {
+		t.Errorf("unexpected result 1: %+v", results[1])
+	}
+	if !strings.Contains(results[2].Error, "status code 429") {
+		t.Errorf("expected status code error for item 2, got %q", results[2].Error)
+	}
+	if results[3].Error != "no result returned by batch" {
+		t.Errorf("expected missing result error for item 3, got %q", results[3].Error)
+	}
+}
+
+func TestRunBatchAPIUnfinishedBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		timeout     time.Duration
+		wantErr     string
+		wantMissing string
+	}{
+		{
+			name: "expired", status: batchStatusExpired, timeout: 5 * time.Second,
+			wantErr: "status expired", wantMissing: "no result returned by batch (status: expired)",
+		},
+		{
+			name: "timed out", status: "in_progress", timeout: 50 * time.Millisecond,
+			wantErr: "timed out", wantMissing: "batch batch_123 did not finish (status: in_progress)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeBatchAPI{
+				status: tt.status,
+				respond: func([]openai.BatchChatCompletionRequest) ([]string, []string) {
+					// Only the first request was processed before the batch stopped
+					return []string{batchSuccessLine("item-0", "done")}, nil
+				},
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			dir := t.TempDir()
+			outputFile := filepath.Join(dir, "output")
+			if err := os.WriteFile(outputFile, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("GITHUB_OUTPUT", outputFile)
+			inputs := filepath.Join(dir, "items.jsonl")
+			if err := os.WriteFile(inputs, []byte("\"a\"\n\"b\"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			config := &Config{
+				BaseURL:           server.URL,
+				APIKey:            "test-key",
+				InputPrompt:       "{{.item}}",
+				BatchInputs:       inputs,
+				BatchAPI:          true,
+				BatchPollInterval: 10 * time.Millisecond,
+				BatchTimeout:      tt.timeout,
+			}
+			client, err := NewClient(config)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			err = runBatch(context.Background(), config, client, client, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runBatch() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+
+			content, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputs := string(content)
+			for _, want := range []string{"batch_id=batch_123", "batch_status=" + tt.status, "batch_failed="} {
+				if !strings.Contains(outputs, want) {
+					t.Errorf("outputs = %q, want them to contain %q", outputs, want)
+				}
+			}
+			if !strings.Contains(outputs, tt.wantMissing) {
+				t.Errorf("outputs = %q, want the unanswered item error %q", outputs, tt.wantMissing)
+			}
+			if tt.status == batchStatusExpired && !strings.Contains(outputs, `"response":"done"`) {
+				t.Errorf("outputs = %q, want the processed item of the expired batch", outputs)
+			}
+		})
+	}
+}
+
+func TestWaitForBatchTimeout(t *testing.T) {
+	fake := &fakeBatchAPI{status: "in_progress"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := NewClient(&Config{BaseURL: server.URL, APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = waitForBatch(
+		context.Background(), client,
+		openai.Batch{ID: "batch_123", Status: "validating"},
+		10*time.Millisecond, 50*time.Millisecond,
+	)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestBatchCustomID(t *testing.T) {
+	if id := batchCustomID(7); id != "item-7" {
+		t.Errorf("expected item-7, got %s", id)
+	}
+
+	tests := []struct {
+		id    string
+		index int
+		ok    bool
+	}{
+		{"item-7", 7, true},
+		{"item-x", 0, false},
+		{"other-1", 0, false},
+	}
+	for _, tt := range tests {
+		index, ok := parseBatchCustomID(tt.id)
+		if index != tt.index || ok != tt.ok {
+			t.Errorf("parseBatchCustomID(%q) = %d, %v; want %d, %v", tt.id, index, ok, tt.index, tt.ok)
+		}
+	}
+}
+
+func TestDecodeBatchLine(t *testing.T) {
+	var line batchOutputLine
+	if err := json.Unmarshal([]byte(`{"custom_id": "item-0", "error": {"code": "invalid_request", "message": "bad"}}`), &line); err != nil {
+		t.Fatalf("failed to parse line: %v", err)
+	}
+	if _, err := decodeBatchLine(line); err == nil || !strings.Contains(err.Error(), "invalid_request") {
+		t.Errorf("expected request error, got %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(batchSuccessLine("item-1", "ok")), &line); err != nil {
+		t.Fatalf("failed to parse line: %v", err)
+	}
+	line.Error = nil
+	resp, err := decodeBatchLine(line)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "ok" {
+		t.Errorf("unexpected content: %q", resp.Choices[0].Message.Content)
+	}
+}
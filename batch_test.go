@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadBatchItemsFromJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inputs.jsonl")
+	content := `{"title": "first"}
+
+{"title": "second"}
+"plain string"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write inputs: %v", err)
+	}
+
+	items, err := loadBatchItems(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+
+	first, ok := items[0].Data.(map[string]any)
+	if !ok || first["title"] != "first" {
+		t.Errorf("unexpected first item: %#v", items[0].Data)
+	}
+	if items[1].Index != 1 || items[1].Source != path+":3" {
+		t.Errorf("unexpected second item: %+v", items[1])
+	}
+	if items[2].Data != "plain string" {
+		t.Errorf("unexpected third item: %#v", items[2].Data)
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatalf("failed to write inputs: %v", err)
+	}
+	if _, err := loadBatchItems(path); err == nil {
+		t.Error("expected error for invalid JSONL")
+	}
+}
+
+func TestLoadBatchItemsFromGlob(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":     "package a",
+		"b.go":     "package b",
+		"notes.md": "# notes",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	root := filepath.ToSlash(tmpDir)
+	// The second pattern overlaps with the first and must not duplicate items
+	items, err := loadBatchItems(root + "/*.go\n" + root + "/a.go\n" + root + "/*.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+
+	data := items[0].Data.(map[string]any)
+	if data["name"] != "a.go" || data["content"] != "package a" {
+		t.Errorf("unexpected first item data: %#v", data)
+	}
+	if items[2].Index != 2 || !strings.HasSuffix(items[2].Source, "notes.md") {
+		t.Errorf("unexpected last item: %+v", items[2])
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var nilLimiter *rateLimiter
+	if err := nilLimiter.Wait(context.Background()); err != nil {
+		t.Errorf("nil limiter should not block: %v", err)
+	}
+	if newRateLimiter(0) != nil {
+		t.Error("expected nil limiter when rate limit is 0")
+	}
+
+	// 1200 requests per minute = one request every 50ms
+	limiter := newRateLimiter(1200)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected at least 100ms for 3 requests, got %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow := newRateLimiter(1)
+	_ = slow.Wait(ctx) // first request is immediate
+	if err := slow.Wait(ctx); err == nil {
+		t.Error("expected context error while waiting")
+	}
+}
+
+func TestRunBatchItems(t *testing.T) {
+	server := newEchoServer(t, nil, nil)
+	defer server.Close()
+
+	outputDir := t.TempDir()
+	config := &Config{
+		BaseURL:        server.URL,
+		APIKey:         "test-key",
+		Model:          "gpt-4o",
+		InputPrompt:    "Summarize {{.item.title}} (#{{.index}})",
+		BatchOutputDir: outputDir,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	items := []BatchItem{
+		{Index: 0, Source: "one", Data: map[string]any{"title": "first"}},
+		{Index: 1, Source: "two", Data: map[string]any{"title": "second"}},
+		{Index: 2, Source: "three", Data: map[string]any{"title": "third"}},
+	}
+	runner := &batchRunner{client: client, judgeClient: client, config: config}
+	results := runBatchItems(context.Background(), runner, items, 2)
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for i, r := range results {
+		if r.Error != "" {
+			t.Fatalf("item %d failed: %s", i, r.Error)
+		}
+		if r.Index != i {
+			t.Errorf("expected results in item order, got index %d at %d", r.Index, i)
+		}
+		if r.Usage.TotalTokens != 5 {
+			t.Errorf("expected 5 tokens, got %d", r.Usage.TotalTokens)
+		}
+	}
+	if results[1].Response != "gpt-4o: Summarize second (#1)" {
+		t.Errorf("unexpected response: %q", results[1].Response)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "0002.txt"))
+	if err != nil {
+		t.Fatalf("expected per-item result file: %v", err)
+	}
+	if string(content) != "gpt-4o: Summarize third (#2)" {
+		t.Errorf("unexpected result file content: %q", content)
+	}
+}
+
+func TestRunBatchItemsPartialFailure(t *testing.T) {
+	server := newEchoServer(t, nil, nil)
+	defer server.Close()
+
+	config := &Config{
+		BaseURL:     server.URL,
+		APIKey:      "test-key",
+		InputPrompt: "Length: {{len .item}}",
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	items := []BatchItem{
+		{Index: 0, Data: "abc"},
+		{Index: 1, Data: 42.0}, // len of a number fails to render
+		{Index: 2, Data: []any{1, 2}},
+	}
+	runner := &batchRunner{client: client, judgeClient: client, config: config}
+	results := runBatchItems(context.Background(), runner, items, 1)
+
+	if results[0].Error != "" || results[2].Error != "" {
+		t.Errorf("expected items 0 and 2 to succeed, got %q and %q", results[0].Error, results[2].Error)
+	}
+	if !strings.Contains(results[1].Error, "failed to render input_prompt") {
+		t.Errorf("expected render error for item 1, got %q", results[1].Error)
+	}
+	if results[2].Response != ": Length: 2" {
+		t.Errorf("unexpected response: %q", results[2].Response)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/appleboy/com/gh"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Ways of splitting the pull request into completions
+const (
+	chunkModeFile  = "file"
+	chunkModeHunks = "hunks"
+)
+
+// chunkModes lists the valid chunk_mode values
+var chunkModes = []string{chunkModeFile, chunkModeHunks}
+
+// findingsField is the tool schema array property merged across chunks
+const findingsField = "findings"
+
+// findingTextFields are the finding properties compared when de-duplicating, in order of preference
+var findingTextFields = []string{"message", "title", "body", "description", "comment"}
+
+// runChunks reviews every changed file, or group of hunks, of the pull request in
+// parallel and sets the merged findings as the outputs
+func runChunks(
+	ctx context.Context,
+	config *Config,
+	client *openai.Client,
+	judgeClient *openai.Client,
+	toolMeta *ToolMeta,
+) error {
+	items, err := prepareChunks(config, toolMeta)
+	if err != nil {
+		return err
+	}
+
+	logInfo("chunks_started",
+		fmt.Sprintf("Reviewing %d chunks (mode: %s, concurrency: %d)...",
+			len(items), config.ChunkMode, config.BatchConcurrency),
+		"chunks", len(items), "mode", config.ChunkMode, "concurrency", config.BatchConcurrency)
+
+	runner := &batchRunner{
+		client:      client,
+		judgeClient: judgeClient,
+		config:      config,
+		toolMeta:    toolMeta,
+		limiter:     newRateLimiter(config.BatchRateLimit),
+	}
+	results := runBatchItems(ctx, runner, items, config.BatchConcurrency)
+
+	return finishChunks(config, items, results)
+}
+
+// prepareChunks checks that chunk mode can run and splits the changed files into items
+func prepareChunks(config *Config, toolMeta *ToolMeta) ([]BatchItem, error) {
+	if err := checkFindingsSchema(toolMeta); err != nil {
+		return nil, err
+	}
+	if config.judgeEnabled() {
+		return nil, errors.New("judge_rubric is not supported in chunk_mode")
+	}
+	if config.Context != "" {
+		return nil, errors.New("context is not supported in chunk_mode, each chunk already includes its diff")
+	}
+
+	opts, err := loadDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+	files, skipped, err := loadPRDiffFiles(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pull request changes: %w", err)
+	}
+	for _, file := range skipped {
+		logInfo("chunk_skipped", fmt.Sprintf("Skipping %s (%s)", file.path, file.reason),
+			"path", file.path, "reason", file.reason)
+	}
+
+	return splitChunks(files, config.ChunkMode, opts.maxFileTokens), nil
+}
+
+// checkFindingsSchema requires a tool schema with a findings array property
+func checkFindingsSchema(toolMeta *ToolMeta) error {
+	if toolMeta != nil {
+		properties, _ := toolMeta.Parameters["properties"].(map[string]any)
+		findings, _ := properties[findingsField].(map[string]any)
+		if findings["type"] == "array" {
+			return nil
+		}
+	}
+	return fmt.Errorf("chunk_mode requires a tool_schema with a %q array property", findingsField)
+}
+
+// splitChunks creates one item per file, or per group of hunks within the token budget.
+// Each item exposes the file to the input prompt template and carries its diff as context.
+func splitChunks(files []diffFile, mode string, maxTokens int) []BatchItem {
+	var items []BatchItem
+	for _, file := range files {
+		patches := []string{truncatePatch(file.Patch, maxTokens)}
+		if mode == chunkModeHunks {
+			patches = splitHunks(file.Patch, maxTokens)
+		}
+
+		for i, patch := range patches {
+			source := file.Path
+			if len(patches) > 1 {
+				source = fmt.Sprintf("%s (part %d/%d)", file.Path, i+1, len(patches))
+			}
+			items = append(items, BatchItem{
+				Index:  len(items),
+				Source: source,
+				Data: map[string]any{
+					"path":          file.Path,
+					"previous_path": file.PreviousPath,
+					"status":        file.Status,
+					"additions":     file.Additions,
+					"deletions":     file.Deletions,
+					"patch":         patch,
+					"part":          i + 1,
+					"parts":         len(patches),
+				},
+				Context: formatDiffFile(file, patch),
+			})
+		}
+	}
+	return items
+}
+
+// splitHunks groups consecutive hunks of a patch within the token budget.
+// A hunk larger than the budget is truncated and gets a group of its own.
+func splitHunks(patch string, maxTokens int) []string {
+	var hunks []string
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "@@") || len(hunks) == 0 {
+			hunks = append(hunks, line)
+			continue
+		}
+		hunks[len(hunks)-1] += "\n" + line
+	}
+
+	var groups []string
+	for _, hunk := range hunks {
+		hunk = truncatePatch(hunk, maxTokens)
+		last := len(groups) - 1
+		if last >= 0 && estimateTokens(groups[last]+"\n"+hunk) <= maxTokens {
+			groups[last] += "\n" + hunk
+			continue
+		}
+		groups = append(groups, hunk)
+	}
+	return groups
+}
+
+// finishChunks merges the findings of the chunks, reports the results and sets the
+// outputs. The step only fails when every chunk failed.
+func finishChunks(config *Config, items []BatchItem, results []BatchResult) error {
+	merged, duplicates := mergeChunkResults(items, results)
+
+	var usage openai.Usage
+	failed := 0
+	redactions := 0
+	for _, r := range results {
+		usage = sumUsage(usage, r.Usage)
+		redactions += r.Redactions
+		if r.Error != "" {
+			failed++
+			warnf("chunk %d (%s) failed: %s", r.Index, r.Source, r.Error)
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal merged findings: %w", err)
+	}
+	response := string(data)
+	findings, _ := merged[findingsField].([]any)
+	logInfo("chunks_merged", fmt.Sprintf("Merged %d findings (%d duplicates removed)", len(findings), duplicates),
+		"findings", len(findings), "duplicates", duplicates)
+
+	printResponse(response)
+
+	printTokenUsage(usage)
+
+	toolArgs, err := ParseFunctionArguments(response)
+	if err != nil {
+		return err
+	}
+	output, _ := BuildOutputMap(response, toolArgs)
+
+	chunkResults, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chunk results: %w", err)
+	}
+	output["chunk_results"] = string(chunkResults)
+	output["chunks_succeeded"] = fmt.Sprint(len(results) - failed)
+	output["chunks_failed"] = fmt.Sprint(failed)
+	output["redactions"] = fmt.Sprint(redactions)
+	addTokenUsageToOutput(output, usage)
+	addCostToOutput(output, config, usage)
+
+	if err := gh.SetOutput(output); err != nil {
+		return fmt.Errorf("failed to set output: %w", err)
+	}
+
+	if failed > 0 && failed == len(results) {
+		return fmt.Errorf("all %d chunks failed", failed)
+	}
+	return nil
+}
+
+// mergeChunkResults merges the tool call arguments of successful chunks in chunk order.
+// Findings are concatenated without duplicates and default to the path of their chunk,
+// other string fields are joined by blank lines, other arrays are concatenated, and any
+// other field keeps its first value. Chunks with invalid arguments are marked as failed.
+func mergeChunkResults(items []BatchItem, results []BatchResult) (map[string]any, int) {
+	merged := map[string]any{findingsField: []any{}}
+	seen := make(map[string]bool)
+	duplicates := 0
+
+	for i := range results {
+		r := &results[i]
+		if r.Error != "" {
+			continue
+		}
+
+		var args map[string]any
+		if err := json.Unmarshal([]byte(r.Response), &args); err != nil {
+			r.Error = fmt.Sprintf("invalid tool call arguments: %v", err)
+			continue
+		}
+
+		path, _ := items[i].Data.(map[string]any)["path"].(string)
+		for key, value := range args {
+			switch existing := merged[key].(type) {
+			case nil:
+				if key != findingsField {
+					merged[key] = value
+				}
+			case string:
+				if s, ok := value.(string); ok && s != "" && !slices.Contains(strings.Split(existing, "\n\n"), s) {
+					merged[key] = strings.TrimPrefix(existing+"\n\n"+s, "\n\n")
+				}
+			case []any:
+				if key != findingsField {
+					if values, ok := value.([]any); ok {
+						merged[key] = append(existing, values...)
+					}
+				}
+			}
+
+			if key != findingsField {
+				continue
+			}
+			findings, _ := value.([]any)
+			for _, finding := range findings {
+				if f, ok := finding.(map[string]any); ok && f["path"] == nil && f["file"] == nil {
+					f["path"] = path
+				}
+				k := findingKey(finding)
+				if seen[k] {
+					duplicates++
+					continue
+				}
+				seen[k] = true
+				merged[findingsField] = append(merged[findingsField].([]any), finding)
+			}
+		}
+	}
+	return merged, duplicates
+}
+
+// findingKey identifies a finding by its path, line and normalized text, or by its
+// JSON encoding when it has no text field
+func findingKey(finding any) string {
+	if f, ok := finding.(map[string]any); ok {
+		for _, field := range findingTextFields {
+			text, _ := f[field].(string)
+			if text == "" {
+				continue
+			}
+			text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+			return fmt.Sprintf("%v\x00%v\x00%s\x00%s",
+				firstOf(f, "path", "file"), firstOf(f, "line", "start_line"), field, text)
+		}
+	}
+	data, _ := json.Marshal(finding)
+	return string(data)
+}
+
+// firstOf returns the first set value of the keys
+func firstOf(m map[string]any, keys ...string) any {
+	for _, key := range keys {
+		if v, ok := m[key]; ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
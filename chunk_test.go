@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const testFindingsSchema = `{
+  "name": "review",
+  "parameters": {
+    "type": "object",
+    "properties": {
+      "summary": {"type": "string"},
+      "findings": {"type": "array", "items": {"type": "object"}}
+    }
+  }
+}`
+
+func TestSplitHunks(t *testing.T) {
+	patch := "@@ -1 +1 @@\n-a\n+b\n@@ -10 +10 @@\n-c\n+d\n@@ -20,3 +20,40 @@\n" +
+		strings.Repeat("+long line\n", 40) + "+end"
+
+	got := splitHunks(patch, 20)
+	if len(got) != 2 {
+		t.Fatalf("splitHunks() returned %d groups, want 2: %q", len(got), got)
+	}
+	if got[0] != "@@ -1 +1 @@\n-a\n+b\n@@ -10 +10 @@\n-c\n+d" {
+		t.Errorf("first group = %q, want the two small hunks", got[0])
+	}
+	if !strings.HasPrefix(got[1], "@@ -20,3 +20,40 @@\n+long line\n") ||
+		!strings.Contains(got[1], "more lines truncated") {
+		t.Errorf("second group = %q, want the truncated large hunk", got[1])
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	files := []diffFile{
+		{Path: "a.go", Status: "modified", Patch: "@@ -1 +1 @@\n+a\n@@ -9 +9 @@\n+b"},
+		{Path: "b.go", Status: "added", Patch: "@@ -0,0 +1 @@\n+c"},
+	}
+
+	items := splitChunks(files, chunkModeFile, 100)
+	if len(items) != 2 || items[0].Source != "a.go" || items[1].Index != 1 {
+		t.Fatalf("file mode items = %+v", items)
+	}
+	if !strings.HasPrefix(items[0].Context, "### a.go (modified, +0 -0)\n\n```diff\n@@ -1 +1 @@") {
+		t.Errorf("Context = %q, want the fenced diff of a.go", items[0].Context)
+	}
+
+	items = splitChunks(files, chunkModeHunks, 4)
+	var sources []string
+	for _, item := range items {
+		sources = append(sources, item.Source)
+	}
+	if strings.Join(sources, ",") != "a.go (part 1/2),a.go (part 2/2),b.go" {
+		t.Errorf("hunk mode sources = %v", sources)
+	}
+	if data := items[1].Data.(map[string]any); data["patch"] != "@@ -9 +9 @@\n+b" || data["part"] != 2 {
+		t.Errorf("hunk mode data = %v", data)
+	}
+}
+
+func TestMergeChunkResults(t *testing.T) {
+	items := []BatchItem{
+		{Data: map[string]any{"path": "a.go"}},
+		{Data: map[string]any{"path": "b.go"}},
+		{Data: map[string]any{"path": "c.go"}},
+		{Data: map[string]any{"path": "d.go"}},
+	}
+	results := []BatchResult{
+		{Response: `{"summary": "A looks fine", "findings": [
+			{"line": 3, "message": "Unchecked error"},
+			{"line": 3, "message": "unchecked   ERROR"},
+			{"path": "x.go", "message": "Unchecked error"}
+		], "labels": ["bug"]}`},
+		{Response: `{"summary": "B needs work", "findings": [
+			{"line": 3, "message": "Unchecked error"}
+		], "labels": ["docs"]}`},
+		{Error: "chat completion error"},
+		{Response: "not json"},
+	}
+
+	merged, duplicates := mergeChunkResults(items, results)
+
+	if duplicates != 1 {
+		t.Errorf("duplicates = %d, want 1", duplicates)
+	}
+	data, _ := json.Marshal(merged)
+	want := `{"findings":[{"line":3,"message":"Unchecked error","path":"a.go"},` +
+		`{"message":"Unchecked error","path":"x.go"},{"line":3,"message":"Unchecked error","path":"b.go"}],` +
+		`"labels":["bug","docs"],"summary":"A looks fine\n\nB needs work"}`
+	if string(data) != want {
+		t.Errorf("merged = %s, want %s", data, want)
+	}
+	if !strings.Contains(results[3].Error, "invalid tool call arguments") {
+		t.Errorf("invalid response error = %q", results[3].Error)
+	}
+}
+
+func TestPrepareChunksErrors(t *testing.T) {
+	findings, err := ParseToolSchema(testFindingsSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noFindings, err := ParseToolSchema(`{"name": "review", "parameters": {"properties": {"summary": {"type": "string"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		toolMeta *ToolMeta
+		wantErr  string
+	}{
+		{"no tool schema", &Config{}, nil, `requires a tool_schema with a "findings" array`},
+		{"no findings", &Config{}, noFindings, `requires a tool_schema with a "findings" array`},
+		{"judge", &Config{JudgeRubric: "Be strict"}, findings, "judge_rubric is not supported"},
+		{"context", &Config{Context: "## Pull request diff"}, findings, "context is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := prepareChunks(tt.config, tt.toolMeta)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("prepareChunks() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newFindingsServer replies with a tool call reporting one shared and one file specific
+// finding for the file named in the diff heading of the prompt
+func newFindingsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	heading := regexp.MustCompile(`### (\S+)`)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		match := heading.FindStringSubmatch(prompt)
+		if match == nil {
+			t.Errorf("prompt %q does not contain a diff", prompt)
+			return
+		}
+		args, _ := json.Marshal(map[string]any{
+			"summary": "Reviewed " + match[1],
+			"findings": []map[string]any{
+				{"path": "shared.go", "message": "Shared problem"},
+				{"line": 1, "message": strings.SplitN(prompt, "\n", 2)[0]},
+			},
+		})
+
+		resp := openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role: openai.ChatMessageRoleAssistant,
+				ToolCalls: []openai.ToolCall{{
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: req.Tools[0].Function.Name, Arguments: string(args)},
+				}},
+			}}},
+			Usage: openai.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestRunChunks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	server := newFindingsServer(t)
+	defer server.Close()
+
+	dir := setupWorkspace(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	t.Setenv("GITHUB_EVENT_PATH", "")
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "base")
+	base := runGit(t, dir, "rev-parse", "HEAD")
+	writeTestFiles(t, dir, map[string]string{
+		"a.go":      "package a\n\nfunc A() {}\n",
+		"b.go":      "package b\n\nfunc B() {}\n",
+		"yarn.lock": "# lockfile\n",
+	})
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "change")
+
+	outputFile := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	t.Setenv("INPUT_BASE_URL", server.URL)
+	t.Setenv("INPUT_API_KEY", "test-key")
+	t.Setenv("INPUT_INPUT_PROMPT", "Review {{.item.path}} ({{.item.status}})")
+	t.Setenv("INPUT_TOOL_SCHEMA", testFindingsSchema)
+	t.Setenv("INPUT_CHUNK_MODE", "file")
+	t.Setenv("INPUT_CONTEXT_SOURCE", "git")
+	t.Setenv("INPUT_CONTEXT_BASE", base)
+	t.Setenv("INPUT_INPUT_TOKEN_PRICE", "2.5")
+	t.Setenv("INPUT_OUTPUT_TOKEN_PRICE", "10")
+
+	if err := run(); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read outputs: %v", err)
+	}
+	output := string(content)
+	wantFindings := `[{"message":"Shared problem","path":"shared.go"},` +
+		`{"line":1,"message":"Review a.go (modified)","path":"a.go"},` +
+		`{"line":1,"message":"Review b.go (modified)","path":"b.go"}]`
+	for _, want := range []string{
+		"findings=" + wantFindings,
+		"\nReviewed a.go\n\nReviewed b.go\n",
+		"chunks_succeeded=2", "total_tokens=240", "cost=0.000900",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("outputs = %q, want them to contain %q", output, want)
+		}
+	}
+}
+
+func TestParseChunkMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		batch   string
+		want    string
+		wantErr string
+	}{
+		{name: "disabled"},
+		{name: "file", mode: "File", want: chunkModeFile},
+		{name: "hunks", mode: " hunks ", want: chunkModeHunks},
+		{name: "invalid", mode: "lines", wantErr: "invalid chunk_mode value"},
+		{name: "batch", mode: "file", batch: "items.jsonl", wantErr: "cannot be combined with batch_inputs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{BatchInputs: tt.batch}
+			err := config.parseChunkMode(tt.mode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseChunkMode() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseChunkMode() unexpected error: %v", err)
+			}
+			if config.ChunkMode != tt.want {
+				t.Errorf("ChunkMode = %q, want %q", config.ChunkMode, tt.want)
+			}
+		})
+	}
+}
@@ -97,7 +97,7 @@ func createHTTPClient(
 	// Always wrap transport with headers (default + custom)
 	allHeaders := mergeHeaders(customHeaders)
 	finalTransport := &headerTransport{
-		base:    baseTransport,
+		base:    &tracingTransport{base: baseTransport},
 		headers: allHeaders,
 	}
 
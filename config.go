@@ -4,39 +4,95 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
 	errAPIKeyRequired      = errors.New("api_key is required")
 	errInputPromptRequired = errors.New("input_prompt is required")
+	errJudgeRubricRequired = errors.New("judge_rubric is required when min_score is set")
+	errBatchInputsRequired = errors.New("batch_inputs is required when batch_api is enabled")
+	errJudgeAPIKeyRequired = errors.New("judge_api_key is required when judge_base_url differs from base_url")
+	errJudgeBatchInputs    = errors.New("judge_rubric and min_score are not supported with batch_inputs")
 )
 
+// templateInputs lists the inputs rendered as Go templates
+var templateInputs = []string{"input_prompt", "system_prompt", "tool_schema", "judge_rubric"}
+
 // Config holds all configuration for the LLM action
 type Config struct {
-	BaseURL       string
-	APIKey        string
-	Model         string
-	SkipSSLVerify bool
-	CACert        string
-	SystemPrompt  string
-	InputPrompt   string
-	ToolSchema    string
-	Temperature   float64
-	MaxTokens     int
-	Debug         bool
-	Headers       map[string]string
+	BaseURL           string
+	APIKey            string
+	Model             string
+	SkipSSLVerify     bool
+	CACert            string
+	SystemPrompt      string
+	InputPrompt       string
+	ToolSchema        string
+	Temperature       float64
+	MaxTokens         int
+	Debug             bool
+	Headers           map[string]string
+	N                 int
+	SelectionStrategy string
+	JudgeRubric       string
+	JudgeModel        string
+	JudgeBaseURL      string
+	JudgeAPIKey       string
+	MinScore          float64
+	BatchInputs       string
+	BatchConcurrency  int
+	BatchRateLimit    int
+	BatchOutputDir    string
+	BatchAPI          bool
+	BatchPollInterval time.Duration
+	BatchTimeout      time.Duration
+	TemplateDisabled  map[string]bool
+	TemplateStrict    bool
+	SecretScan        bool
+	Redact            bool
+	RedactPatterns    []*regexp.Regexp
+	RedactRestore     bool
+	PromptSource      map[string]string
+	PromptSeparator   string
+	PromptFormat      string
+	PromptMaxBytes    int64
+	Context           string
+	ChunkMode         string
+	InputTokenPrice   float64
+	OutputTokenPrice  float64
+	DryRun            bool
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
+	return loadConfig(true)
+}
+
+// loadConfig loads configuration from environment variables, rendering the inputs
+// that support Go templates when render is set
+func loadConfig(render bool) (*Config, error) {
 	config := &Config{
-		BaseURL:     os.Getenv("INPUT_BASE_URL"),
-		APIKey:      os.Getenv("INPUT_API_KEY"),
-		Model:       os.Getenv("INPUT_MODEL"),
-		Temperature: 0.7,  // default
-		MaxTokens:   1000, // default
+		BaseURL:           os.Getenv("INPUT_BASE_URL"),
+		APIKey:            os.Getenv("INPUT_API_KEY"),
+		Model:             os.Getenv("INPUT_MODEL"),
+		Temperature:       0.7,  // default
+		MaxTokens:         1000, // default
+		N:                 1,    // default
+		SelectionStrategy: SelectionFirst,
+		JudgeModel:        os.Getenv("INPUT_JUDGE_MODEL"),
+		JudgeBaseURL:      os.Getenv("INPUT_JUDGE_BASE_URL"),
+		JudgeAPIKey:       os.Getenv("INPUT_JUDGE_API_KEY"),
+		BatchInputs:       strings.TrimSpace(os.Getenv("INPUT_BATCH_INPUTS")),
+		BatchConcurrency:  4, // default
+		BatchOutputDir:    os.Getenv("INPUT_BATCH_OUTPUT_DIR"),
+		BatchPollInterval: 30 * time.Second, // default
+		BatchTimeout:      6 * time.Hour,    // default
+		SecretScan:        true,             // default
 	}
 
 	// Set default base URL if not provided
@@ -44,17 +100,59 @@ func LoadConfig() (*Config, error) {
 		config.BaseURL = "https://api.openai.com/v1"
 	}
 
+	// A dry run never contacts the provider, so it works without an API key
+	if err := config.parseDryRun(os.Getenv("INPUT_DRY_RUN")); err != nil {
+		return nil, err
+	}
+
 	// Validate required inputs
-	if config.APIKey == "" {
+	if config.APIKey == "" && !config.DryRun {
 		return nil, errAPIKeyRequired
 	}
+	if err := config.checkJudgeAPIKey(); err != nil {
+		return nil, err
+	}
+
+	// Inputs with templating disabled are loaded verbatim
+	if err := config.parseTemplateDisable(os.Getenv("INPUT_TEMPLATE_DISABLE")); err != nil {
+		return nil, err
+	}
+	if err := config.parseTemplateStrict(os.Getenv("INPUT_TEMPLATE_STRICT")); err != nil {
+		return nil, err
+	}
+	if err := config.parsePromptSource(os.Getenv("INPUT_PROMPT_SOURCE")); err != nil {
+		return nil, err
+	}
+	if err := config.parsePromptCompose(); err != nil {
+		return nil, err
+	}
+	if err := config.parseChunkMode(os.Getenv("INPUT_CHUNK_MODE")); err != nil {
+		return nil, err
+	}
+	// Inputs are loaded as their prompt_source kind, and inputs with a companion
+	// <name>_sha256 input are pinned to that digest
+	loaderFor := func(input string, render bool) func(string) (string, error) {
+		kind := config.promptSource(input)
+		if kind == sourceList {
+			return traceLoader(input, kind, config.composerFor(input, render))
+		}
+		return traceLoader(input, kind, pinLoader(input, func(s string) (string, error) {
+			content, err := loadContentAs(kind, s)
+			if err != nil || !render || !config.templateEnabled(input) {
+				return content, err
+			}
+			return renderPrompt(content, config.TemplateStrict)
+		}))
+	}
 
 	// Load input prompt (supports text, file path, or URL)
 	inputPromptInput := os.Getenv("INPUT_INPUT_PROMPT")
 	if inputPromptInput == "" {
 		return nil, errInputPromptRequired
 	}
-	loadedInputPrompt, err := LoadPrompt(inputPromptInput)
+	// In batch and chunk mode the input prompt is rendered once per item instead
+	perItem := config.BatchInputs != "" || config.ChunkMode != ""
+	loadedInputPrompt, err := loaderFor("input_prompt", render && !perItem)(inputPromptInput)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load input_prompt: %w", err)
 	}
@@ -63,7 +161,7 @@ func LoadConfig() (*Config, error) {
 	// Load system prompt (supports text, file path, or URL)
 	systemPromptInput := os.Getenv("INPUT_SYSTEM_PROMPT")
 	if systemPromptInput != "" {
-		loadedPrompt, err := LoadPrompt(systemPromptInput)
+		loadedPrompt, err := loaderFor("system_prompt", render)(systemPromptInput)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load system_prompt: %w", err)
 		}
@@ -73,7 +171,7 @@ func LoadConfig() (*Config, error) {
 	// Load CA certificate (supports content, file path, or URL)
 	caCertInput := os.Getenv("INPUT_CA_CERT")
 	if caCertInput != "" {
-		loadedCACert, err := LoadContent(caCertInput)
+		loadedCACert, err := loadCACert(caCertInput)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load ca_cert: %w", err)
 		}
@@ -83,13 +181,31 @@ func LoadConfig() (*Config, error) {
 	// Load tool schema (supports text, file path, or URL with template rendering)
 	toolSchemaInput := os.Getenv("INPUT_TOOL_SCHEMA")
 	if toolSchemaInput != "" {
-		loadedSchema, err := LoadPrompt(toolSchemaInput)
+		loadedSchema, err := loaderFor("tool_schema", render)(toolSchemaInput)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load tool_schema: %w", err)
 		}
 		config.ToolSchema = loadedSchema
 	}
 
+	// Load judge rubric (supports text, file path, or URL with template rendering)
+	judgeRubricInput := os.Getenv("INPUT_JUDGE_RUBRIC")
+	if judgeRubricInput != "" && config.BatchInputs != "" {
+		return nil, errJudgeBatchInputs
+	}
+	if judgeRubricInput != "" {
+		loadedRubric, err := loaderFor("judge_rubric", render)(judgeRubricInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load judge_rubric: %w", err)
+		}
+		config.JudgeRubric = loadedRubric
+	}
+
+	// Load context providers such as the pull request diff, appended to the user prompt
+	if err := config.loadContext(os.Getenv("INPUT_CONTEXT")); err != nil {
+		return nil, err
+	}
+
 	// Parse optional parameters
 	if err := config.parseTemperature(os.Getenv("INPUT_TEMPERATURE")); err != nil {
 		return nil, err
@@ -111,6 +227,66 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.parseN(os.Getenv("INPUT_N")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseSelectionStrategy(os.Getenv("INPUT_SELECTION_STRATEGY")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseMinScore(os.Getenv("INPUT_MIN_SCORE")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseBatchConcurrency(os.Getenv("INPUT_BATCH_CONCURRENCY")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseBatchRateLimit(os.Getenv("INPUT_BATCH_RATE_LIMIT")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseBatchAPI(os.Getenv("INPUT_BATCH_API")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseBatchPollInterval(os.Getenv("INPUT_BATCH_POLL_INTERVAL")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseBatchTimeout(os.Getenv("INPUT_BATCH_TIMEOUT")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseSecretScan(os.Getenv("INPUT_SECRET_SCAN")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseRedact(os.Getenv("INPUT_REDACT")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseRedactPatterns(os.Getenv("INPUT_REDACT_PATTERNS")); err != nil {
+		return nil, err
+	}
+
+	if err := config.parseRedactRestore(os.Getenv("INPUT_REDACT_RESTORE")); err != nil {
+		return nil, err
+	}
+
+	if config.InputTokenPrice, err = parseTokenPrice(
+		"input_token_price", os.Getenv("INPUT_INPUT_TOKEN_PRICE"),
+	); err != nil {
+		return nil, err
+	}
+
+	if config.OutputTokenPrice, err = parseTokenPrice(
+		"output_token_price", os.Getenv("INPUT_OUTPUT_TOKEN_PRICE"),
+	); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
@@ -145,6 +321,188 @@ func (c *Config) parseMaxTokens(s string) error {
 	return nil
 }
 
+// parseN parses the number of completions to request
+func (c *Config) parseN(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid n value: %w", err)
+	}
+	if n < 1 {
+		return fmt.Errorf("n must be at least 1")
+	}
+	c.N = n
+	return nil
+}
+
+// parseSelectionStrategy parses the candidate selection strategy
+func (c *Config) parseSelectionStrategy(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	switch s {
+	case SelectionFirst, SelectionMajorityVote, SelectionLongest, SelectionJudge:
+		c.SelectionStrategy = s
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid selection_strategy value: %q (expected one of: %s)",
+			s, strings.Join(selectionStrategies, ", "),
+		)
+	}
+}
+
+// parseMinScore parses the minimum judge score required for the step to pass
+func (c *Config) parseMinScore(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid min_score value: %w", err)
+	}
+	if score < 0 || score > judgeMaxScore {
+		return fmt.Errorf("min_score must be between 0 and %d", judgeMaxScore)
+	}
+	if c.BatchInputs != "" {
+		return errJudgeBatchInputs
+	}
+	if c.JudgeRubric == "" {
+		return errJudgeRubricRequired
+	}
+	c.MinScore = score
+	return nil
+}
+
+// parseChunkMode parses how pull request changes are split into separate completions
+func (c *Config) parseChunkMode(s string) error {
+	mode := strings.ToLower(strings.TrimSpace(s))
+	if mode == "" {
+		return nil
+	}
+	if !slices.Contains(chunkModes, mode) {
+		return fmt.Errorf("invalid chunk_mode value: %q (expected one of: %s)", mode, strings.Join(chunkModes, ", "))
+	}
+	if c.BatchInputs != "" {
+		return errors.New("chunk_mode cannot be combined with batch_inputs")
+	}
+	if c.DryRun {
+		return errors.New("chunk_mode cannot be combined with dry_run")
+	}
+	c.ChunkMode = mode
+	return nil
+}
+
+// parseTokenPrice parses a price in USD per million tokens
+func parseTokenPrice(name, s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	price, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value: %w", name, err)
+	}
+	if price < 0 {
+		return 0, fmt.Errorf("%s must not be negative", name)
+	}
+	return price, nil
+}
+
+// parseBatchConcurrency parses the maximum number of concurrent batch requests
+func (c *Config) parseBatchConcurrency(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid batch_concurrency value: %w", err)
+	}
+	if n < 1 {
+		return fmt.Errorf("batch_concurrency must be at least 1")
+	}
+	c.BatchConcurrency = n
+	return nil
+}
+
+// parseBatchRateLimit parses the batch rate limit in requests per minute (0 disables it)
+func (c *Config) parseBatchRateLimit(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid batch_rate_limit value: %w", err)
+	}
+	if n < 0 {
+		return fmt.Errorf("batch_rate_limit must not be negative")
+	}
+	c.BatchRateLimit = n
+	return nil
+}
+
+// parseBatchAPI parses whether batch items are submitted through the Batch API
+func (c *Config) parseBatchAPI(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid batch_api value: %w", err)
+	}
+	if enabled && c.BatchInputs == "" {
+		return errBatchInputsRequired
+	}
+	c.BatchAPI = enabled
+	return nil
+}
+
+// parseBatchPollInterval parses how often the Batch API job status is polled
+func (c *Config) parseBatchPollInterval(s string) error {
+	d, err := parsePositiveDuration("batch_poll_interval", s)
+	if err != nil || d == 0 {
+		return err
+	}
+	c.BatchPollInterval = d
+	return nil
+}
+
+// parseBatchTimeout parses how long to wait for a Batch API job to finish
+func (c *Config) parseBatchTimeout(s string) error {
+	d, err := parsePositiveDuration("batch_timeout", s)
+	if err != nil || d == 0 {
+		return err
+	}
+	c.BatchTimeout = d
+	return nil
+}
+
+// parsePositiveDuration parses a Go duration string such as "30s" or "2h".
+// An empty string returns zero so the caller keeps its default.
+func parsePositiveDuration(name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value: %w", name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
+
 // parseSkipSSL parses skip SSL verify string to bool
 func (c *Config) parseSkipSSL(s string) error {
 	if s == "" {
@@ -173,14 +531,253 @@ func (c *Config) parseDebug(s string) error {
 	return nil
 }
 
+// parseTemplateDisable parses the comma or newline separated inputs that skip template rendering
+func (c *Config) parseTemplateDisable(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	c.TemplateDisabled = make(map[string]bool)
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(templateInputs, name) {
+			return fmt.Errorf(
+				"invalid template_disable value: %q (expected one of: %s)",
+				name, strings.Join(templateInputs, ", "),
+			)
+		}
+		c.TemplateDisabled[name] = true
+	}
+	return nil
+}
+
+// parseTemplateStrict parses whether templates fail on missing keys
+func (c *Config) parseTemplateStrict(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	strict, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid template_strict value: %w", err)
+	}
+	c.TemplateStrict = strict
+	return nil
+}
+
+// parsePromptSource parses comma or newline separated source kinds. A bare kind such as
+// "text" applies to every prompt input, "name=kind" to a single input.
+func (c *Config) parsePromptSource(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	c.PromptSource = make(map[string]string)
+	var fallback string
+	for _, entry := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, kind, found := strings.Cut(entry, "=")
+		if !found {
+			name, kind = "", name
+		}
+		name, kind = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(kind))
+		if name != "" && !slices.Contains(templateInputs, name) {
+			return fmt.Errorf(
+				"invalid prompt_source value: %q (expected one of: %s)",
+				name, strings.Join(templateInputs, ", "),
+			)
+		}
+		if !slices.Contains(sourceKinds, kind) {
+			return fmt.Errorf(
+				"invalid prompt_source value: %q (expected one of: %s)",
+				kind, strings.Join(sourceKinds, ", "),
+			)
+		}
+
+		if name == "" {
+			fallback = kind
+		} else {
+			c.PromptSource[name] = kind
+		}
+	}
+
+	if fallback != "" {
+		for _, name := range templateInputs {
+			if _, ok := c.PromptSource[name]; !ok {
+				c.PromptSource[name] = fallback
+			}
+		}
+	}
+	return nil
+}
+
+// promptSource returns the source kind of the named input, detected automatically by default
+func (c *Config) promptSource(input string) string {
+	if kind, ok := c.PromptSource[input]; ok {
+		return kind
+	}
+	return sourceAuto
+}
+
+// parsePromptCompose parses the prompt_separator, prompt_source_format and
+// prompt_max_bytes inputs used for inputs composed from a list of sources
+func (c *Config) parsePromptCompose() error {
+	c.PromptSeparator = "\n\n"
+	if s := os.Getenv("INPUT_PROMPT_SEPARATOR"); s != "" {
+		// Allow escaped newlines and tabs in single line YAML values
+		c.PromptSeparator = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(s)
+	}
+
+	c.PromptFormat = composeFormatPlain
+	if s := strings.TrimSpace(os.Getenv("INPUT_PROMPT_SOURCE_FORMAT")); s != "" {
+		if !slices.Contains(composeFormats, s) {
+			return fmt.Errorf(
+				"invalid prompt_source_format value: %q (expected one of: %s)",
+				s, strings.Join(composeFormats, ", "),
+			)
+		}
+		c.PromptFormat = s
+	}
+
+	maxBytes, err := parseByteLimit("prompt_max_bytes", os.Getenv("INPUT_PROMPT_MAX_BYTES"), defaultPromptMaxBytes)
+	if err != nil {
+		return err
+	}
+	c.PromptMaxBytes = maxBytes
+	return nil
+}
+
+// composerFor returns a loader that composes the named input from a list of sources.
+// Each source is pinned individually, so the companion <name>_sha256 input is refused.
+func (c *Config) composerFor(input string, render bool) func(string) (string, error) {
+	composer := &promptComposer{
+		separator: c.PromptSeparator,
+		format:    c.PromptFormat,
+		maxBytes:  c.PromptMaxBytes,
+		render:    render && c.templateEnabled(input),
+		strict:    c.TemplateStrict,
+	}
+	return func(s string) (string, error) {
+		if os.Getenv("INPUT_"+strings.ToUpper(input)+"_SHA256") != "" {
+			return "", fmt.Errorf(
+				"%s_sha256 is not supported for a list of sources, pin each source with a #sha256- suffix", input,
+			)
+		}
+		return composer.compose(s)
+	}
+}
+
+// templateEnabled reports whether the named input is rendered as a Go template
+func (c *Config) templateEnabled(input string) bool {
+	return !c.TemplateDisabled[input]
+}
+
+// parseSecretScan parses secret scan string to bool
+func (c *Config) parseSecretScan(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	scan, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid secret_scan value: %w", err)
+	}
+	c.SecretScan = scan
+	return nil
+}
+
+// parseRedact parses redact string to bool
+func (c *Config) parseRedact(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	redact, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid redact value: %w", err)
+	}
+	c.Redact = redact
+	return nil
+}
+
+// parseRedactPatterns compiles the newline separated regular expressions of redact_patterns
+func (c *Config) parseRedactPatterns(s string) error {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return fmt.Errorf("invalid redact_patterns value: %w", err)
+		}
+		c.RedactPatterns = append(c.RedactPatterns, re)
+	}
+	return nil
+}
+
+// parseDryRun parses dry run string to bool
+func (c *Config) parseDryRun(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	dryRun, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid dry_run value: %w", err)
+	}
+	if dryRun && c.BatchInputs != "" {
+		return errors.New("dry_run cannot be combined with batch_inputs")
+	}
+	c.DryRun = dryRun
+	return nil
+}
+
+// parseRedactRestore parses redact restore string to bool
+func (c *Config) parseRedactRestore(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	restore, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid redact_restore value: %w", err)
+	}
+	c.RedactRestore = restore
+	return nil
+}
+
 // parseHeaders parses headers string to map
 // Format: "Header1:Value1,Header2:Value2" or multiline "Header1:Value1\nHeader2:Value2"
 func (c *Config) parseHeaders(s string) error {
+	headers, err := parseHeaderList(s)
+	if err != nil {
+		return err
+	}
+	c.Headers = headers
+	return nil
+}
+
+// parseHeaderList parses comma or newline separated "Key:Value" pairs, returning nil for
+// an empty string
+func parseHeaderList(s string) (map[string]string, error) {
 	if s == "" {
-		return nil
+		return nil, nil
 	}
 
-	c.Headers = make(map[string]string)
+	headers := make(map[string]string)
 
 	// Support both comma-separated and newline-separated formats
 	// First normalize newlines to commas for consistent parsing
@@ -196,18 +793,18 @@ func (c *Config) parseHeaders(s string) error {
 		// Split on first colon only (value may contain colons)
 		idx := strings.Index(pair, ":")
 		if idx == -1 {
-			return fmt.Errorf("invalid header format: %q (expected 'Key:Value')", pair)
+			return nil, fmt.Errorf("invalid header format: %q (expected 'Key:Value')", pair)
 		}
 
 		key := strings.TrimSpace(pair[:idx])
 		value := strings.TrimSpace(pair[idx+1:])
 
 		if key == "" {
-			return fmt.Errorf("empty header key in: %q", pair)
+			return nil, fmt.Errorf("empty header key in: %q", pair)
 		}
 
-		c.Headers[key] = value
+		headers[key] = value
 	}
 
-	return nil
+	return headers, nil
 }
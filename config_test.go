@@ -5,7 +5,9 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // testCACertContent is a sample CA certificate content for testing
@@ -244,6 +246,371 @@ func TestConfigParseMaxTokens(t *testing.T) {
 	}
 }
 
+func TestConfigParseN(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    int
+		expectError bool
+	}{
+		{"Valid n", "3", 3, false},
+		{"Empty string", "", 1, false}, // should keep default
+		{"Invalid n", "abc", 0, true},
+		{"Zero n", "0", 0, true},
+		{"Negative n", "-1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{N: 1}
+			err := config.parseN(tt.input)
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && config.N != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, config.N)
+			}
+		})
+	}
+}
+
+func TestConfigParseSelectionStrategy(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+	}{
+		{"Empty string", "", SelectionFirst, false}, // should keep default
+		{"First", "first", SelectionFirst, false},
+		{"Majority vote", "majority-vote", SelectionMajorityVote, false},
+		{"Longest", "longest", SelectionLongest, false},
+		{"Judge with spaces", " judge ", SelectionJudge, false},
+		{"Unknown strategy", "random", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{SelectionStrategy: SelectionFirst}
+			err := config.parseSelectionStrategy(tt.input)
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && config.SelectionStrategy != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, config.SelectionStrategy)
+			}
+		})
+	}
+}
+
+func TestConfigParseMinScore(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		rubric      string
+		expected    float64
+		expectError bool
+	}{
+		{"Empty string", "", "", 0, false}, // should keep default
+		{"Valid score", "7.5", "Be accurate", 7.5, false},
+		{"Max score", "10", "Be accurate", 10, false},
+		{"Above max", "11", "Be accurate", 0, true},
+		{"Negative score", "-1", "Be accurate", 0, true},
+		{"Invalid score", "high", "Be accurate", 0, true},
+		{"Missing rubric", "5", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{JudgeRubric: tt.rubric}
+			err := config.parseMinScore(tt.input)
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && config.MinScore != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, config.MinScore)
+			}
+		})
+	}
+}
+
+func TestConfigParseBatchConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    int
+		expectError bool
+	}{
+		{"Empty string", "", 4, false}, // should keep default
+		{"Valid value", "8", 8, false},
+		{"Zero", "0", 0, true},
+		{"Invalid value", "many", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{BatchConcurrency: 4}
+			err := config.parseBatchConcurrency(tt.input)
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && config.BatchConcurrency != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, config.BatchConcurrency)
+			}
+		})
+	}
+}
+
+func TestConfigParseBatchRateLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    int
+		expectError bool
+	}{
+		{"Empty string", "", 0, false}, // should keep default
+		{"Valid value", "60", 60, false},
+		{"Zero disables", "0", 0, false},
+		{"Negative", "-1", 0, true},
+		{"Invalid value", "fast", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			err := config.parseBatchRateLimit(tt.input)
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && config.BatchRateLimit != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, config.BatchRateLimit)
+			}
+		})
+	}
+}
+
+func TestLoadConfigBatchModeKeepsInputTemplate(t *testing.T) {
+	os.Setenv("INPUT_API_KEY", "test-key")
+	os.Setenv("INPUT_INPUT_PROMPT", "Summarize {{.item.path}}")
+	os.Setenv("INPUT_BATCH_INPUTS", "src/**/*.go")
+	defer func() {
+		os.Unsetenv("INPUT_API_KEY")
+		os.Unsetenv("INPUT_INPUT_PROMPT")
+		os.Unsetenv("INPUT_BATCH_INPUTS")
+	}()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.InputPrompt != "Summarize {{.item.path}}" {
+		t.Errorf("expected unrendered input prompt in batch mode, got %q", config.InputPrompt)
+	}
+	if config.BatchInputs != "src/**/*.go" || config.BatchConcurrency != 4 {
+		t.Errorf("unexpected batch settings: %q, %d", config.BatchInputs, config.BatchConcurrency)
+	}
+}
+
+func TestLoadConfigBatchModeRejectsJudge(t *testing.T) {
+	tests := []struct {
+		name     string
+		rubric   string
+		minScore string
+	}{
+		{name: "judge_rubric", rubric: "Be accurate"},
+		{name: "min_score", minScore: "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_API_KEY", "test-key")
+			t.Setenv("INPUT_INPUT_PROMPT", "Summarize {{.item.path}}")
+			t.Setenv("INPUT_BATCH_INPUTS", "src/**/*.go")
+			t.Setenv("INPUT_JUDGE_RUBRIC", tt.rubric)
+			t.Setenv("INPUT_MIN_SCORE", tt.minScore)
+
+			if _, err := LoadConfig(); err != errJudgeBatchInputs {
+				t.Errorf("LoadConfig() error = %v, want %v", err, errJudgeBatchInputs)
+			}
+		})
+	}
+}
+
+func TestConfigParseTemplateDisable(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]bool
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"input_prompt", map[string]bool{"input_prompt": true}, false},
+		{"input_prompt, system_prompt", map[string]bool{"input_prompt": true, "system_prompt": true}, false},
+		{"tool_schema\njudge_rubric\n", map[string]bool{"tool_schema": true, "judge_rubric": true}, false},
+		{"api_key", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			config := &Config{}
+			err := config.parseTemplateDisable(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTemplateDisable(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(config.TemplateDisabled) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, config.TemplateDisabled)
+			}
+			for name := range tt.expected {
+				if config.templateEnabled(name) {
+					t.Errorf("expected templating disabled for %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigTemplateStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		strict   string
+		disable  string
+		wantErr  string
+		wantText string
+	}{
+		{name: "default", wantText: "Review <no value>"},
+		{name: "strict", strict: "true", wantErr: `map has no entry for key "TEMPLATE_STRICT_MISSING"`},
+		{name: "invalid", strict: "sometimes", wantErr: "invalid template_strict value"},
+		{
+			name: "invalid with templates disabled", strict: "sometimes",
+			disable: "input_prompt, system_prompt", wantErr: "invalid template_strict value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_API_KEY", "test-key")
+			t.Setenv("INPUT_INPUT_PROMPT", "Review {{.TEMPLATE_STRICT_MISSING}}")
+			t.Setenv("INPUT_TEMPLATE_STRICT", tt.strict)
+			t.Setenv("INPUT_TEMPLATE_DISABLE", tt.disable)
+
+			config, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			if config.InputPrompt != tt.wantText {
+				t.Errorf("InputPrompt = %q, want %q", config.InputPrompt, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithTemplateDisabled(t *testing.T) {
+	os.Setenv("INPUT_API_KEY", "test-key")
+	os.Setenv("INPUT_INPUT_PROMPT", "Explain {{ .Values.image }} in this Helm chart")
+	os.Setenv("INPUT_SYSTEM_PROMPT", "You review {{.TEMPLATE_DISABLE_TEST}} charts")
+	os.Setenv("INPUT_TEMPLATE_DISABLE", "input_prompt")
+	os.Setenv("TEMPLATE_DISABLE_TEST", "Helm")
+	defer func() {
+		os.Unsetenv("INPUT_API_KEY")
+		os.Unsetenv("INPUT_INPUT_PROMPT")
+		os.Unsetenv("INPUT_SYSTEM_PROMPT")
+		os.Unsetenv("INPUT_TEMPLATE_DISABLE")
+		os.Unsetenv("TEMPLATE_DISABLE_TEST")
+	}()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.InputPrompt != "Explain {{ .Values.image }} in this Helm chart" {
+		t.Errorf("expected input prompt to be loaded verbatim, got %q", config.InputPrompt)
+	}
+	if config.SystemPrompt != "You review Helm charts" {
+		t.Errorf("expected system prompt to be rendered, got %q", config.SystemPrompt)
+	}
+}
+
+func TestConfigParseBatchAPI(t *testing.T) {
+	config := &Config{}
+	if err := config.parseBatchAPI("true"); err != errBatchInputsRequired {
+		t.Errorf("expected errBatchInputsRequired, got %v", err)
+	}
+
+	config.BatchInputs = "inputs.jsonl"
+	if err := config.parseBatchAPI("true"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.BatchAPI {
+		t.Error("expected batch_api to be enabled")
+	}
+
+	if err := config.parseBatchAPI("maybe"); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+func TestConfigParseBatchDurations(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{"Empty string", "", 30 * time.Second, false}, // should keep default
+		{"Seconds", "10s", 10 * time.Second, false},
+		{"Hours", "2h", 2 * time.Hour, false},
+		{"Zero", "0s", 0, true},
+		{"Negative", "-1m", 0, true},
+		{"Missing unit", "30", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{BatchPollInterval: 30 * time.Second, BatchTimeout: 30 * time.Second}
+			errInterval := config.parseBatchPollInterval(tt.input)
+			errTimeout := config.parseBatchTimeout(tt.input)
+
+			if tt.expectError && (errInterval == nil || errTimeout == nil) {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && (errInterval != nil || errTimeout != nil) {
+				t.Errorf("unexpected error: %v, %v", errInterval, errTimeout)
+			}
+			if !tt.expectError && (config.BatchPollInterval != tt.expected || config.BatchTimeout != tt.expected) {
+				t.Errorf("expected %v, got %v and %v", tt.expected, config.BatchPollInterval, config.BatchTimeout)
+			}
+		})
+	}
+}
+
 // boolParseTestCase defines test cases for boolean parsing functions
 type boolParseTestCase struct {
 	name        string
@@ -560,6 +927,25 @@ func TestConfigParseDebug(t *testing.T) {
 	}
 }
 
+func TestConfigParseSecretScan(t *testing.T) {
+	for _, tt := range getBoolParseTestCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{SecretScan: false}
+			err := config.parseSecretScan(tt.input)
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.expectError && config.SecretScan != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, config.SecretScan)
+			}
+		})
+	}
+}
+
 // testToolSchemaContent is a sample tool schema JSON for testing
 const testToolSchemaContent = `{
   "name": "get_city_info",
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Context providers accepted by the context input
+const contextPRDiff = "pr_diff"
+
+// contextProviders lists the valid context values
+var contextProviders = []string{contextPRDiff}
+
+// Where the pull request diff is read from
+const (
+	diffSourceAuto = "auto"
+	diffSourceAPI  = "api"
+	diffSourceGit  = "git"
+)
+
+// Defaults for the pull request diff context
+const (
+	defaultContextMaxFileTokens = 2000
+	defaultContextMaxTokens     = 20000
+
+	// prFilesPerPage and prFilesMaxPages follow the limits of the pull request files API
+	prFilesPerPage  = 100
+	prFilesMaxPages = 30
+)
+
+// defaultDiffExcludes drop lockfiles and generated or vendored code. Patterns without
+// a slash match the file name at any depth.
+var defaultDiffExcludes = []struct {
+	reason   string
+	patterns []string
+}{
+	{"lockfile", []string{
+		"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+		"go.sum", "Cargo.lock", "Gemfile.lock", "poetry.lock", "Pipfile.lock", "composer.lock",
+		"uv.lock", "mix.lock", "pubspec.lock", "Podfile.lock", "packages.lock.json",
+	}},
+	{"generated", []string{
+		"vendor/**", "node_modules/**", "dist/**", "*.min.js", "*.min.css", "*.map",
+		"*.pb.go", "*_generated.go", "*.gen.go", "*.snap", "*.g.dart", "*_pb2.py",
+	}},
+}
+
+// generatedMarker matches the conventional headers of generated files in added lines
+var generatedMarker = regexp.MustCompile(`(?m)^\+.*(Code generated .* DO NOT EDIT|@generated)`)
+
+// diffFile is a changed file of a pull request
+type diffFile struct {
+	Path         string `json:"filename"`
+	PreviousPath string `json:"previous_filename"`
+	Status       string `json:"status"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	Patch        string `json:"patch"`
+}
+
+// skippedFile is a changed file left out of the context
+type skippedFile struct {
+	path   string
+	reason string
+}
+
+// diffOptions configure the pull request diff context
+type diffOptions struct {
+	source        string
+	base          string
+	include       []string
+	exclude       []string
+	maxFileTokens int
+	maxTokens     int
+}
+
+// loadContext loads the context providers named by the context input into c.Context,
+// which is appended to the user prompt
+func (c *Config) loadContext(s string) error {
+	for _, provider := range splitList(s) {
+		provider = strings.ToLower(provider)
+		switch provider {
+		case contextPRDiff:
+			opts, err := loadDiffOptions()
+			if err != nil {
+				return err
+			}
+			content, err := loadPRDiffContext(opts)
+			if err != nil {
+				return fmt.Errorf("failed to load context %s: %w", provider, err)
+			}
+			c.Context = content
+		default:
+			return fmt.Errorf(
+				"invalid context value: %q (expected one of: %s)",
+				provider, strings.Join(contextProviders, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// loadDiffOptions parses the context_* inputs
+func loadDiffOptions() (diffOptions, error) {
+	opts := diffOptions{
+		source:        strings.ToLower(strings.TrimSpace(os.Getenv("INPUT_CONTEXT_SOURCE"))),
+		base:          strings.TrimSpace(os.Getenv("INPUT_CONTEXT_BASE")),
+		include:       splitList(os.Getenv("INPUT_CONTEXT_INCLUDE")),
+		exclude:       splitList(os.Getenv("INPUT_CONTEXT_EXCLUDE")),
+		maxFileTokens: defaultContextMaxFileTokens,
+		maxTokens:     defaultContextMaxTokens,
+	}
+	switch opts.source {
+	case "":
+		opts.source = diffSourceAuto
+	case diffSourceAuto, diffSourceAPI, diffSourceGit:
+	default:
+		return opts, fmt.Errorf("invalid context_source value: %q (expected auto, api or git)", opts.source)
+	}
+
+	var err error
+	if opts.maxFileTokens, err = parseTokenBudget(
+		"context_max_file_tokens", os.Getenv("INPUT_CONTEXT_MAX_FILE_TOKENS"), defaultContextMaxFileTokens,
+	); err != nil {
+		return opts, err
+	}
+	if opts.maxTokens, err = parseTokenBudget(
+		"context_max_tokens", os.Getenv("INPUT_CONTEXT_MAX_TOKENS"), defaultContextMaxTokens,
+	); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// splitList splits a comma or newline separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTokenBudget parses a positive token count, returning def when s is empty
+func parseTokenBudget(name, s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value: %w", name, err)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return n, nil
+}
+
+// estimateTokens approximates the token count of text at four characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// loadPRDiffContext loads, filters and formats the changed files of the pull request
+func loadPRDiffContext(opts diffOptions) (string, error) {
+	kept, skipped, err := loadPRDiffFiles(opts)
+	if err != nil {
+		return "", err
+	}
+	return formatDiffContext(kept, skipped, opts), nil
+}
+
+// loadPRDiffFiles loads the changed files of the pull request and filters them,
+// returning the files to keep and the files left out
+func loadPRDiffFiles(opts diffOptions) ([]diffFile, []skippedFile, error) {
+	event := loadEventPayload()
+	pr, _ := event["pull_request"].(map[string]any)
+
+	source := opts.source
+	if source == diffSourceAuto {
+		source = diffSourceGit
+		if pr != nil && githubToken() != "" {
+			source = diffSourceAPI
+		}
+	}
+
+	var files []diffFile
+	var err error
+	if source == diffSourceAPI {
+		files, err = loadPRFilesFromAPI(event)
+	} else {
+		files, err = loadPRFilesFromGit(opts.base, pr)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return filterDiffFiles(files, opts)
+}
+
+// loadPRFilesFromAPI lists the changed files of the pull request in the event payload
+func loadPRFilesFromAPI(event map[string]any) ([]diffFile, error) {
+	number := prNumber(event)
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if number == 0 || repo == "" {
+		return nil, fmt.Errorf(
+			"no pull request found in the event payload (use context_source: git outside pull request events)",
+		)
+	}
+
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+
+	var files []diffFile
+	for page := 1; page <= prFilesMaxPages; page++ {
+		u := fmt.Sprintf(
+			"%s/repos/%s/pulls/%d/files?per_page=%d&page=%d",
+			strings.TrimSuffix(apiURL, "/"), repo, number, prFilesPerPage, page,
+		)
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request for %s: %w", u, err)
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if token := githubToken(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		body, err := fetchContent(req, u)
+		if err != nil {
+			return nil, err
+		}
+		var batch []diffFile
+		if err := json.Unmarshal([]byte(body), &batch); err != nil {
+			return nil, fmt.Errorf("failed to parse pull request files: %w", err)
+		}
+		files = append(files, batch...)
+		if len(batch) < prFilesPerPage {
+			break
+		}
+	}
+	return files, nil
+}
+
+// prNumber returns the pull request number of the event payload, or 0
+func prNumber(event map[string]any) int {
+	if pr, ok := event["pull_request"].(map[string]any); ok {
+		if n, ok := pr["number"].(float64); ok {
+			return int(n)
+		}
+	}
+	if n, ok := event["number"].(float64); ok {
+		return int(n)
+	}
+	return 0
+}
+
+// loadPRFilesFromGit diffs the local checkout against the merge base with the base ref.
+// The base defaults to the pull request base commit, then origin/GITHUB_BASE_REF.
+func loadPRFilesFromGit(base string, pr map[string]any) ([]diffFile, error) {
+	if base == "" {
+		if prBase, ok := pr["base"].(map[string]any); ok {
+			base, _ = prBase["sha"].(string)
+		}
+	}
+	if base == "" && os.Getenv("GITHUB_BASE_REF") != "" {
+		base = "origin/" + os.Getenv("GITHUB_BASE_REF")
+	}
+	if base == "" {
+		return nil, fmt.Errorf("no base ref to diff against (set context_base)")
+	}
+	// Refuse refs that git would parse as options
+	if strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("invalid context_base value: %q", base)
+	}
+
+	out, err := gitOutput("diff", "--no-color", "--no-ext-diff", "--find-renames", base+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", base, err)
+	}
+	return parseUnifiedDiff(out), nil
+}
+
+// parseUnifiedDiff splits git diff output into files with their hunks as the patch,
+// like the patches returned by the GitHub API
+func parseUnifiedDiff(diff string) []diffFile {
+	var files []diffFile
+	var current *diffFile
+	inHunks := false
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, diffFile{Status: "modified"})
+			current = &files[len(files)-1]
+			inHunks = false
+			// "diff --git a/path b/path" names the file until ---/+++ lines say otherwise
+			if _, b, found := strings.Cut(line, " b/"); found {
+				current.Path = b
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if inHunks {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				current.Additions++
+			case strings.HasPrefix(line, "-"):
+				current.Deletions++
+			}
+			current.Patch += line + "\n"
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunks = true
+			current.Patch += line + "\n"
+		case strings.HasPrefix(line, "new file mode"):
+			current.Status = "added"
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.Status = "removed"
+		case strings.HasPrefix(line, "rename from "):
+			current.Status = "renamed"
+			current.PreviousPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			current.Path = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "+++ b/"):
+			current.Path = strings.TrimPrefix(line, "+++ b/")
+		case strings.HasPrefix(line, "--- a/") && current.Status == "removed":
+			current.Path = strings.TrimPrefix(line, "--- a/")
+		}
+	}
+
+	for i := range files {
+		files[i].Patch = strings.TrimSuffix(files[i].Patch, "\n")
+	}
+	return files
+}
+
+// matchPathPattern matches a slash separated path against a glob pattern. Patterns
+// without a slash match the file name in any directory, like .gitignore.
+func matchPathPattern(pattern, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re.MatchString(name), nil
+}
+
+// matchAnyPath reports whether the path matches one of the patterns
+func matchAnyPath(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		ok, err := matchPathPattern(pattern, name)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// filterDiffFiles applies the include and exclude patterns, drops lockfiles and
+// generated code, and reports why files were skipped. Files outside the include
+// patterns are dropped silently.
+func filterDiffFiles(files []diffFile, opts diffOptions) ([]diffFile, []skippedFile, error) {
+	var kept []diffFile
+	var skipped []skippedFile
+	for _, file := range files {
+		if len(opts.include) > 0 {
+			ok, err := matchAnyPath(opts.include, file.Path)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		reason, err := diffSkipReason(file, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reason != "" {
+			skipped = append(skipped, skippedFile{file.Path, reason})
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, skipped, nil
+}
+
+// diffSkipReason returns why a file is left out of the context, or an empty string
+func diffSkipReason(file diffFile, opts diffOptions) (string, error) {
+	if ok, err := matchAnyPath(opts.exclude, file.Path); err != nil || ok {
+		return "excluded", err
+	}
+	// Explicitly included files are kept even if they look generated
+	if ok, err := matchAnyPath(opts.include, file.Path); err != nil || ok {
+		return "", err
+	}
+
+	for _, group := range defaultDiffExcludes {
+		if ok, err := matchAnyPath(group.patterns, file.Path); err != nil || ok {
+			return group.reason, err
+		}
+	}
+	if generatedMarker.MatchString(file.Patch) {
+		return "generated", nil
+	}
+	return "", nil
+}
+
+// truncatePatch keeps whole lines of the patch within the token budget
+func truncatePatch(patch string, maxTokens int) string {
+	if estimateTokens(patch) <= maxTokens {
+		return patch
+	}
+
+	lines := strings.Split(patch, "\n")
+	var b strings.Builder
+	kept := 0
+	for _, line := range lines {
+		if estimateTokens(b.String()+line+"\n") > maxTokens {
+			break
+		}
+		b.WriteString(line + "\n")
+		kept++
+	}
+	fmt.Fprintf(&b, "... (%d more lines truncated)", len(lines)-kept)
+	return b.String()
+}
+
+// formatDiffContext formats the files as fenced diffs within the token budgets,
+// followed by the files that were skipped
+func formatDiffContext(files []diffFile, skipped []skippedFile, opts diffOptions) string {
+	var b strings.Builder
+	b.WriteString("## Pull request diff\n")
+	used := 0
+	for _, file := range files {
+		patch := truncatePatch(file.Patch, opts.maxFileTokens)
+		if used+estimateTokens(patch) > opts.maxTokens {
+			skipped = append(skipped, skippedFile{file.Path, "token budget"})
+			continue
+		}
+		used += estimateTokens(patch)
+		b.WriteString("\n" + formatDiffFile(file, patch) + "\n")
+	}
+
+	if len(files) == 0 {
+		b.WriteString("\nNo changed files.\n")
+	}
+	if len(skipped) > 0 {
+		b.WriteString("\nSkipped files:\n")
+		for _, file := range skipped {
+			fmt.Fprintf(&b, "- %s (%s)\n", file.path, file.reason)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatDiffFile formats a changed file with its patch as a fenced diff
+func formatDiffFile(file diffFile, patch string) string {
+	if patch == "" {
+		patch = "(no textual changes)"
+	}
+	name := file.Path
+	if file.PreviousPath != "" && file.PreviousPath != file.Path {
+		name = file.PreviousPath + " -> " + file.Path
+	}
+	fence := codeFence(patch)
+	return fmt.Sprintf("### %s (%s, +%d -%d)\n\n%sdiff\n%s\n%s",
+		name, file.Status, file.Additions, file.Deletions, fence, patch, fence)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testUnifiedDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+diff --git a/docs/new.md b/docs/new.md
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++--- not a header
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 4444444..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/a.go b/b.go
+similarity index 90%
+rename from a.go
+rename to b.go
+diff --git a/logo.png b/logo.png
+index 5555555..6666666 100644
+Binary files a/logo.png and b/logo.png differ
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	want := []diffFile{
+		{Path: "main.go", Status: "modified", Additions: 1, Deletions: 1,
+			Patch: "@@ -1,3 +1,3 @@\n package main\n-var x = 1\n+var x = 2"},
+		{Path: "docs/new.md", Status: "added", Additions: 2,
+			Patch: "@@ -0,0 +1,2 @@\n+# New\n+--- not a header"},
+		{Path: "old.txt", Status: "removed", Deletions: 1, Patch: "@@ -1 +0,0 @@\n-gone"},
+		{Path: "b.go", PreviousPath: "a.go", Status: "renamed"},
+		{Path: "logo.png", Status: "modified"},
+	}
+
+	got := parseUnifiedDiff(testUnifiedDiff)
+	if len(got) != len(want) {
+		t.Fatalf("parseUnifiedDiff() returned %d files, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFilterDiffFiles(t *testing.T) {
+	files := []diffFile{
+		{Path: "main.go", Patch: "+package main"},
+		{Path: "web/package-lock.json", Patch: "+{}"},
+		{Path: "go.sum", Patch: "+h1:abc"},
+		{Path: "api/service.pb.go", Patch: "+package api"},
+		{Path: "vendor/lib/lib.go", Patch: "+package lib"},
+		{Path: "mocks/store.go", Patch: "+// Code generated by MockGen. DO NOT EDIT.\n+package mocks"},
+		{Path: "docs/readme.md", Patch: "+# Docs"},
+	}
+
+	tests := []struct {
+		name        string
+		include     []string
+		exclude     []string
+		wantKept    []string
+		wantSkipped []string
+	}{
+		{
+			name:     "defaults drop lockfiles and generated code",
+			wantKept: []string{"main.go", "docs/readme.md"},
+			wantSkipped: []string{
+				"web/package-lock.json (lockfile)", "go.sum (lockfile)", "api/service.pb.go (generated)",
+				"vendor/lib/lib.go (generated)", "mocks/store.go (generated)",
+			},
+		},
+		{
+			name:     "include limits files",
+			include:  []string{"docs/**"},
+			wantKept: []string{"docs/readme.md"},
+		},
+		{
+			name:     "explicit include keeps generated files",
+			include:  []string{"main.go", "api/*.pb.go"},
+			wantKept: []string{"main.go", "api/service.pb.go"},
+		},
+		{
+			name:        "exclude",
+			include:     []string{"**/*.md", "main.go"},
+			exclude:     []string{"docs/**"},
+			wantKept:    []string{"main.go"},
+			wantSkipped: []string{"docs/readme.md (excluded)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped, err := filterDiffFiles(files, diffOptions{include: tt.include, exclude: tt.exclude})
+			if err != nil {
+				t.Fatalf("filterDiffFiles() unexpected error: %v", err)
+			}
+
+			var gotKept, gotSkipped []string
+			for _, f := range kept {
+				gotKept = append(gotKept, f.Path)
+			}
+			for _, f := range skipped {
+				gotSkipped = append(gotSkipped, fmt.Sprintf("%s (%s)", f.path, f.reason))
+			}
+			if strings.Join(gotKept, ",") != strings.Join(tt.wantKept, ",") {
+				t.Errorf("kept = %v, want %v", gotKept, tt.wantKept)
+			}
+			if strings.Join(gotSkipped, ",") != strings.Join(tt.wantSkipped, ",") {
+				t.Errorf("skipped = %v, want %v", gotSkipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestFormatDiffContextBudgets(t *testing.T) {
+	var long []string
+	for i := range 100 {
+		long = append(long, fmt.Sprintf("+line %03d", i))
+	}
+	files := []diffFile{
+		{Path: "big.go", Status: "modified", Additions: 100, Patch: strings.Join(long, "\n")},
+		{Path: "small.go", Status: "added", Additions: 1, Patch: "+package small"},
+		{Path: "last.go", Status: "added", Additions: 1, Patch: "+package last"},
+	}
+
+	got := formatDiffContext(files, []skippedFile{{"go.sum", "lockfile"}}, diffOptions{maxFileTokens: 25, maxTokens: 38})
+
+	for _, want := range []string{
+		"## Pull request diff",
+		"### big.go (modified, +100 -0)\n\n```diff\n+line 000\n",
+		"... (90 more lines truncated)\n```",
+		"### small.go (added, +1 -0)",
+		"Skipped files:\n- go.sum (lockfile)\n- last.go (token budget)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatDiffContext() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "+line 010") {
+		t.Errorf("formatDiffContext() did not truncate big.go: %q", got)
+	}
+}
+
+func TestLoadConfigWithPRDiffFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := setupWorkspace(t, map[string]string{
+		"main.go":           "package main\n",
+		"package-lock.json": "{}\n",
+	})
+	t.Setenv("GITHUB_EVENT_PATH", "")
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "base")
+	base := runGit(t, dir, "rev-parse", "HEAD")
+
+	writeTestFiles(t, dir, map[string]string{
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"package-lock.json": "{\"lockfileVersion\": 3}\n",
+		"api/types.pb.go":   "package api\n",
+		"docs/guide.md":     "# Guide {{ not a template }}\n",
+	})
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "change")
+
+	t.Setenv("INPUT_API_KEY", "test-key")
+	t.Setenv("INPUT_INPUT_PROMPT", "Review this pull request")
+	t.Setenv("INPUT_CONTEXT", "pr_diff")
+	t.Setenv("INPUT_CONTEXT_SOURCE", "git")
+	t.Setenv("INPUT_CONTEXT_BASE", base)
+	t.Setenv("INPUT_CONTEXT_EXCLUDE", "docs/**")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"### main.go (modified, +2 -0)",
+		"+func main() {}",
+		"- package-lock.json (lockfile)",
+		"- api/types.pb.go (generated)",
+		"- docs/guide.md (excluded)",
+	} {
+		if !strings.Contains(config.Context, want) {
+			t.Errorf("Context = %q, want it to contain %q", config.Context, want)
+		}
+	}
+
+	messages := BuildMessages(config)
+	if content := messages[0].Content; !strings.HasPrefix(content, "Review this pull request\n\n## Pull request diff") {
+		t.Errorf("user message = %q, want the prompt followed by the diff", content)
+	}
+
+	t.Setenv("INPUT_CONTEXT_BASE", "")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "set context_base") {
+		t.Errorf("LoadConfig() without base error = %v", err)
+	}
+}
+
+func TestLoadConfigWithPRDiffFromAPI(t *testing.T) {
+	var gotAuth string
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/pulls/42/files" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		pages = append(pages, r.URL.Query().Get("page"))
+
+		var files []diffFile
+		if r.URL.Query().Get("page") == "1" {
+			for i := range prFilesPerPage {
+				files = append(files, diffFile{Path: fmt.Sprintf("gen/file%03d.pb.go", i), Status: "added"})
+			}
+		} else {
+			files = append(files,
+				diffFile{Path: "server.go", Status: "modified", Additions: 1, Patch: "@@ -1 +1 @@\n+package server"},
+				diffFile{Path: "yarn.lock", Status: "modified"},
+			)
+		}
+		_ = json.NewEncoder(w).Encode(files)
+	}))
+	defer server.Close()
+
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 42}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("INPUT_GITHUB_TOKEN", "ghs_test")
+	t.Setenv("INPUT_API_KEY", "test-key")
+	t.Setenv("INPUT_INPUT_PROMPT", "Review")
+	t.Setenv("INPUT_CONTEXT", "pr_diff")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+
+	if gotAuth != "Bearer ghs_test" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer ghs_test")
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("requested pages %v, want 1,2", pages)
+	}
+	for _, want := range []string{
+		"### server.go (modified, +1 -0)", "+package server", "- yarn.lock (lockfile)", "- gen/file099.pb.go (generated)",
+	} {
+		if !strings.Contains(config.Context, want) {
+			t.Errorf("Context = %q, want it to contain %q", config.Context, want)
+		}
+	}
+}
+
+func TestLoadContextErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"unknown provider", map[string]string{"INPUT_CONTEXT": "issue_comments"}, "invalid context value"},
+		{"invalid source", map[string]string{"INPUT_CONTEXT": "pr_diff", "INPUT_CONTEXT_SOURCE": "svn"}, "invalid context_source value"},
+		{
+			"invalid budget", map[string]string{"INPUT_CONTEXT": "pr_diff", "INPUT_CONTEXT_MAX_TOKENS": "0"},
+			"context_max_tokens must be positive",
+		},
+		{
+			"api without pull request", map[string]string{"INPUT_CONTEXT": "pr_diff", "INPUT_CONTEXT_SOURCE": "api"},
+			"no pull request found",
+		},
+		{
+			"option-like base", map[string]string{
+				"INPUT_CONTEXT": "pr_diff", "INPUT_CONTEXT_SOURCE": "git", "INPUT_CONTEXT_BASE": "--output=x",
+			},
+			"invalid context_base value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_EVENT_PATH", "")
+			config := &Config{}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			err := config.loadContext(os.Getenv("INPUT_CONTEXT"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadContext() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
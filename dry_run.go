@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/appleboy/com/gh"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// chatCompletionsPath is the endpoint of chat completion requests relative to the base URL
+const chatCompletionsPath = "/chat/completions"
+
+// runDryRun prints the request that would be sent with its estimated token count and
+// sets it as the request_json output, without contacting the provider
+func runDryRun(config *Config, messages []openai.ChatCompletionMessage, toolMeta *ToolMeta) error {
+	if err := scanMessagesForSecrets(config, messages); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(buildChatRequest(config, messages, toolMeta))
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	endpoint := strings.TrimSuffix(config.BaseURL, "/") + chatCompletionsPath
+	apiKey := maskAPIKey(config.APIKey)
+	tokens := estimateTokens(string(body))
+
+	if jsonLogger != nil {
+		jsonLogger.Info("dry_run",
+			"url", endpoint, "api_key", apiKey, "request", json.RawMessage(body), "estimated_tokens", tokens)
+	} else {
+		fmt.Println("--- Dry Run Request ---")
+		fmt.Printf("POST %s\n", endpoint)
+		fmt.Printf("Authorization: Bearer %s\n", apiKey)
+		fmt.Println(string(body))
+		fmt.Println("--- End Dry Run Request ---")
+		fmt.Printf("Estimated Tokens: %d\n", tokens)
+		fmt.Println("Dry run: the request was not sent")
+	}
+
+	output := map[string]string{
+		"request_json":     string(body),
+		"estimated_tokens": strconv.Itoa(tokens),
+	}
+	if err := gh.SetOutput(output); err != nil {
+		return fmt.Errorf("failed to set output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestRunDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run contacted the provider: %s %s", r.Method, r.URL)
+	}))
+	defer server.Close()
+
+	outputFile := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("INPUT_BASE_URL", server.URL)
+	t.Setenv("INPUT_API_KEY", "")
+	t.Setenv("INPUT_MODEL", "gpt-4o")
+	t.Setenv("INPUT_SYSTEM_PROMPT", "You are a reviewer")
+	t.Setenv("INPUT_INPUT_PROMPT", "Review {{.GITHUB_REPOSITORY}}")
+	t.Setenv("INPUT_TOOL_SCHEMA", testFindingsSchema)
+	t.Setenv("INPUT_DRY_RUN", "true")
+
+	if err := run(); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read outputs: %v", err)
+	}
+	outputs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		name, value, _ := strings.Cut(line, "=")
+		outputs[name] = value
+	}
+
+	var req openai.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(outputs["request_json"]), &req); err != nil {
+		t.Fatalf("invalid request_json %q: %v", outputs["request_json"], err)
+	}
+	if req.Model != "gpt-4o" || len(req.Messages) != 2 || req.Messages[1].Content != "Review owner/repo" ||
+		len(req.Tools) != 1 || req.Tools[0].Function.Name != "review" {
+		t.Errorf("request_json = %s", outputs["request_json"])
+	}
+	if want := estimateTokens(outputs["request_json"]); outputs["estimated_tokens"] != strconv.Itoa(want) {
+		t.Errorf("estimated_tokens = %q, want %d", outputs["estimated_tokens"], want)
+	}
+	if _, ok := outputs["response"]; ok {
+		t.Error("expected no response output in a dry run")
+	}
+}
+
+func TestParseDryRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		batch   string
+		want    bool
+		wantErr string
+	}{
+		{name: "empty"},
+		{name: "enabled", value: "true", want: true},
+		{name: "disabled with batch", value: "false", batch: "items.jsonl"},
+		{name: "invalid", value: "maybe", wantErr: "invalid dry_run value"},
+		{name: "batch", value: "true", batch: "items.jsonl", wantErr: "cannot be combined with batch_inputs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{BatchInputs: tt.batch}
+			err := config.parseDryRun(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseDryRun() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDryRun() unexpected error: %v", err)
+			}
+			if config.DryRun != tt.want {
+				t.Errorf("DryRun = %v, want %v", config.DryRun, tt.want)
+			}
+		})
+	}
+
+	if err := (&Config{DryRun: true}).parseChunkMode("file"); err == nil ||
+		!strings.Contains(err.Error(), "cannot be combined with dry_run") {
+		t.Errorf("parseChunkMode() error = %v, want it to reject dry_run", err)
+	}
+}
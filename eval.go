@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var errEvalDatasetRequired = errors.New("eval: -dataset is required")
+
+// EvalCase is a single row of an evaluation dataset
+type EvalCase struct {
+	Name   string          `json:"name"`
+	Vars   map[string]any  `json:"vars"`
+	Assert []EvalAssertion `json:"assert"`
+}
+
+// EvalConfig holds the configuration of the eval subcommand
+type EvalConfig struct {
+	// Base holds connection and generation settings. Prompt fields contain
+	// unrendered templates which are rendered per dataset row.
+	Base           *Config
+	Dataset        string
+	Models         []string
+	Concurrency    int
+	JUnitReport    string
+	MarkdownReport string
+}
+
+// EvalResult is the outcome of running one dataset row against one model
+type EvalResult struct {
+	Case     string
+	Model    string
+	Response string
+	Failures []string
+	Error    string
+	Duration time.Duration
+	Usage    openai.Usage
+}
+
+// Passed reports whether the case completed and all assertions held
+func (r EvalResult) Passed() bool {
+	return r.Error == "" && len(r.Failures) == 0
+}
+
+// loadEvalConfig parses eval subcommand flags on top of the action inputs
+func loadEvalConfig(args []string) (*EvalConfig, error) {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	dataset := fs.String("dataset", "", "JSONL dataset of template variables and assertions (file path or URL)")
+	models := fs.String("models", "", "comma-separated models to evaluate (defaults to INPUT_MODEL)")
+	concurrency := fs.Int("concurrency", 4, "maximum number of concurrent completions")
+	junit := fs.String("junit", "", "path to write the JUnit XML report")
+	markdown := fs.String("markdown", "", "path to write the Markdown report")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *dataset == "" {
+		return nil, errEvalDatasetRequired
+	}
+	if *concurrency < 1 {
+		return nil, fmt.Errorf("eval: -concurrency must be at least 1")
+	}
+
+	// Keep prompts as raw templates so every row can be rendered with its own variables
+	base, err := loadConfig(false)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &EvalConfig{
+		Base:           base,
+		Dataset:        *dataset,
+		Concurrency:    *concurrency,
+		JUnitReport:    *junit,
+		MarkdownReport: *markdown,
+	}
+
+	for _, m := range strings.Split(*models, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			cfg.Models = append(cfg.Models, m)
+		}
+	}
+	if len(cfg.Models) == 0 {
+		if base.Model == "" {
+			return nil, fmt.Errorf("eval: no model configured (use -models or INPUT_MODEL)")
+		}
+		cfg.Models = []string{base.Model}
+	}
+
+	return cfg, nil
+}
+
+// loadEvalDataset loads and parses a JSONL evaluation dataset
+func loadEvalDataset(input string) ([]EvalCase, error) {
+	content, err := LoadContent(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dataset: %w", err)
+	}
+
+	var cases []EvalCase
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var c EvalCase
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("invalid dataset row on line %d: %w", line, err)
+		}
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", line)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset is empty")
+	}
+
+	return cases, nil
+}
+
+// renderEvalConfig renders all template inputs of the base config with the row variables
+func renderEvalConfig(base *Config, c EvalCase, model string) (*Config, error) {
+	rc := *base
+	rc.Model = model
+
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"system_prompt", &rc.SystemPrompt},
+		{"input_prompt", &rc.InputPrompt},
+		{"tool_schema", &rc.ToolSchema},
+		{"judge_rubric", &rc.JudgeRubric},
+	}
+	for _, f := range fields {
+		if *f.value == "" || !base.templateEnabled(f.name) {
+			continue
+		}
+		rendered, err := RenderTemplateWithData(*f.value, c.Vars, base.TemplateStrict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", f.name, err)
+		}
+		*f.value = rendered
+	}
+
+	return &rc, nil
+}
+
+// evalRunner runs dataset rows against the configured models
+type evalRunner struct {
+	client      *openai.Client
+	judgeClient *openai.Client
+	base        *Config
+}
+
+// runCase runs a single dataset row against a single model and checks its assertions
+func (r *evalRunner) runCase(ctx context.Context, c EvalCase, model string) (result EvalResult) {
+	start := time.Now()
+	result = EvalResult{Case: c.Name, Model: model}
+	// The named result lets the deferred call set the duration of every return
+	defer func() { result.Duration = time.Since(start) }()
+
+	config, err := renderEvalConfig(r.base, c, model)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	messages := BuildMessages(config)
+	toolMeta, err := ParseToolSchema(config.ToolSchema)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	completion, err := complete(ctx, r.client, r.judgeClient, config, messages, toolMeta)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Usage = completion.Usage
+	result.Response = completion.Response()
+
+	env := &assertionEnv{
+		judgeClient: r.judgeClient,
+		config:      config,
+		messages:    messages,
+		toolMeta:    toolMeta,
+	}
+	for i, a := range c.Assert {
+		failure, usage, err := env.check(ctx, a, result.Response)
+		result.Usage = sumUsage(result.Usage, usage)
+		if err != nil {
+			result.Error = fmt.Sprintf("assertion %d (%s): %v", i, a.Type, err)
+			return result
+		}
+		if failure != "" {
+			result.Failures = append(result.Failures, failure)
+		}
+	}
+
+	return result
+}
+
+// runEvalCases runs every row against every model with bounded concurrency.
+// Results are returned grouped by model in dataset order.
+func runEvalCases(
+	ctx context.Context,
+	runner *evalRunner,
+	cases []EvalCase,
+	models []string,
+	concurrency int,
+) []EvalResult {
+	results := make([]EvalResult, len(cases)*len(models))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for mi, model := range models {
+		for ci, c := range cases {
+			idx := mi*len(cases) + ci
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[idx] = runner.runCase(ctx, c, model)
+			}()
+		}
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runEval implements the eval subcommand
+func runEval(args []string) error {
+	cfg, err := loadEvalConfig(args)
+	if err != nil {
+		return err
+	}
+
+	finishAudit, err := startAudit()
+	if err != nil {
+		return err
+	}
+	defer finishAudit()
+
+	cases, err := loadEvalDataset(cfg.Dataset)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClient(cfg.Base)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	judgeClient, err := newJudgeClient(cfg.Base, client)
+	if err != nil {
+		return err
+	}
+
+	logInfo("eval_started",
+		fmt.Sprintf("Evaluating %d cases against %d models (concurrency: %d)...",
+			len(cases), len(cfg.Models), cfg.Concurrency),
+		"cases", len(cases), "models", cfg.Models, "concurrency", cfg.Concurrency)
+
+	start := time.Now()
+	runner := &evalRunner{client: client, judgeClient: judgeClient, base: cfg.Base}
+	results := runEvalCases(context.Background(), runner, cases, cfg.Models, cfg.Concurrency)
+	elapsed := time.Since(start)
+
+	// JSON logs keep stdout to log events, the report still goes to the files and job summary
+	report := renderMarkdownReport(results, cfg.Models)
+	if jsonLogger != nil {
+		logEvalResults(results)
+	} else {
+		fmt.Println(report)
+	}
+
+	if cfg.MarkdownReport != "" {
+		if err := os.WriteFile(cfg.MarkdownReport, []byte(report), 0o600); err != nil {
+			return fmt.Errorf("failed to write markdown report: %w", err)
+		}
+	}
+	if cfg.JUnitReport != "" {
+		if err := writeJUnitReport(cfg.JUnitReport, results, cfg.Models, elapsed); err != nil {
+			return err
+		}
+	}
+	if err := appendStepSummary(report); err != nil {
+		warnf("failed to write step summary: %v", err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+	logInfo("eval_finished",
+		fmt.Sprintf("Evaluated %d cases in %s: %d passed, %d failed",
+			len(results), elapsed.Round(time.Millisecond), len(results)-failed, failed),
+		"results", len(results), "passed", len(results)-failed, "failed", failed,
+		"duration_ms", elapsed.Milliseconds())
+	if failed > 0 {
+		return fmt.Errorf("eval failed: %d of %d cases did not pass", failed, len(results))
+	}
+
+	return nil
+}
+
+// logEvalResults emits an event for every result in json mode
+func logEvalResults(results []EvalResult) {
+	for _, r := range results {
+		attrs := []any{
+			"case", r.Case, "model", r.Model, "passed", r.Passed(),
+			"duration_ms", r.Duration.Milliseconds(), usageAttr(r.Usage),
+		}
+		if len(r.Failures) > 0 {
+			attrs = append(attrs, "failures", r.Failures)
+		}
+		if r.Error != "" {
+			attrs = append(attrs, "error", r.Error)
+		}
+		jsonLogger.Info("eval_result", attrs...)
+	}
+}
+
+// appendStepSummary appends the report to the GitHub Actions job summary when available
+func appendStepSummary(report string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, report)
+	return err
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Supported evaluation assertion types
+const (
+	AssertContains    = "contains"
+	AssertRegex       = "regex"
+	AssertJSONField   = "json_field"
+	AssertSchemaValid = "schema_valid"
+	AssertJudgeScore  = "judge_score"
+)
+
+// EvalAssertion is an expectation checked against a response
+type EvalAssertion struct {
+	Type string `json:"type"`
+	// Value is the substring (contains), pattern (regex) or expected value (json_field)
+	Value any `json:"value"`
+	// Field is a dot separated path into the JSON response (json_field)
+	Field string `json:"field"`
+	// Schema overrides the tool schema parameters (schema_valid)
+	Schema map[string]any `json:"schema"`
+	// Rubric overrides judge_rubric (judge_score)
+	Rubric string `json:"rubric"`
+	// MinScore overrides min_score (judge_score)
+	MinScore *float64 `json:"min_score"`
+}
+
+// assertionEnv carries the context needed by assertions that go beyond the response text
+type assertionEnv struct {
+	judgeClient *openai.Client
+	config      *Config
+	messages    []openai.ChatCompletionMessage
+	toolMeta    *ToolMeta
+}
+
+// check evaluates an assertion against the response.
+// It returns a non-empty failure message when the assertion does not hold and an
+// error when the assertion itself cannot be evaluated.
+func (e *assertionEnv) check(
+	ctx context.Context,
+	a EvalAssertion,
+	response string,
+) (string, openai.Usage, error) {
+	switch a.Type {
+	case AssertContains:
+		value, ok := a.Value.(string)
+		if !ok {
+			return "", openai.Usage{}, fmt.Errorf("value must be a string")
+		}
+		if !strings.Contains(response, value) {
+			return fmt.Sprintf("response does not contain %q", value), openai.Usage{}, nil
+		}
+		return "", openai.Usage{}, nil
+
+	case AssertRegex:
+		pattern, ok := a.Value.(string)
+		if !ok {
+			return "", openai.Usage{}, fmt.Errorf("value must be a string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", openai.Usage{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		if !re.MatchString(response) {
+			return fmt.Sprintf("response does not match /%s/", pattern), openai.Usage{}, nil
+		}
+		return "", openai.Usage{}, nil
+
+	case AssertJSONField:
+		return checkJSONField(response, a.Field, a.Value), openai.Usage{}, nil
+
+	case AssertSchemaValid:
+		schema := a.Schema
+		if schema == nil && e.toolMeta != nil {
+			schema = e.toolMeta.Parameters
+		}
+		if schema == nil {
+			return "", openai.Usage{}, fmt.Errorf("no schema given and no tool_schema configured")
+		}
+		var value any
+		if err := json.Unmarshal([]byte(response), &value); err != nil {
+			return fmt.Sprintf("response is not valid JSON: %v", err), openai.Usage{}, nil
+		}
+		if errs := validateSchema(schema, value, "$"); len(errs) > 0 {
+			return "schema validation failed: " + strings.Join(errs, "; "), openai.Usage{}, nil
+		}
+		return "", openai.Usage{}, nil
+
+	case AssertJudgeScore:
+		return e.checkJudgeScore(ctx, a, response)
+
+	default:
+		return "", openai.Usage{}, fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+}
+
+// checkJudgeScore scores the response with the judge and compares it with the minimum score
+func (e *assertionEnv) checkJudgeScore(
+	ctx context.Context,
+	a EvalAssertion,
+	response string,
+) (string, openai.Usage, error) {
+	config := *e.config
+	if a.Rubric != "" {
+		config.JudgeRubric = a.Rubric
+	}
+	if config.JudgeRubric == "" {
+		return "", openai.Usage{}, fmt.Errorf("no rubric given and no judge_rubric configured")
+	}
+	minScore := config.MinScore
+	if a.MinScore != nil {
+		minScore = *a.MinScore
+	}
+
+	result, usage, err := evaluateResponse(ctx, e.judgeClient, &config, e.messages, response)
+	if err != nil {
+		return "", usage, err
+	}
+	if !result.meetsMinScore(minScore) {
+		return fmt.Sprintf(
+			"judge score %v is below %v: %s",
+			result.Score, minScore, strings.Join(result.Reasons, "; "),
+		), usage, nil
+	}
+
+	return "", usage, nil
+}
+
+// checkJSONField compares the value at a dot separated path of the JSON response with the expected value
+func checkJSONField(response, field string, expected any) string {
+	var doc any
+	if err := json.Unmarshal([]byte(response), &doc); err != nil {
+		return fmt.Sprintf("response is not valid JSON: %v", err)
+	}
+
+	actual, ok := lookupJSONPath(doc, field)
+	if !ok {
+		return fmt.Sprintf("field %q not found", field)
+	}
+
+	if canonicalJSON(actual) != canonicalJSON(expected) {
+		return fmt.Sprintf("field %q is %s, expected %s", field, canonicalJSON(actual), canonicalJSON(expected))
+	}
+	return ""
+}
+
+// lookupJSONPath walks a decoded JSON document using a dot separated path.
+// Numeric segments index into arrays. An empty path returns the document itself.
+func lookupJSONPath(doc any, path string) (any, bool) {
+	if path == "" {
+		return doc, true
+	}
+
+	current := doc
+	for _, segment := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// canonicalJSON returns a stable JSON encoding used for value comparison
+func canonicalJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// validateSchema validates a decoded JSON value against a subset of JSON Schema
+// (type, enum, properties, required, additionalProperties, items, minimum, maximum)
+// and returns a list of violations
+func validateSchema(schema map[string]any, value any, path string) []string {
+	var errs []string
+
+	if t, ok := schema["type"]; ok {
+		types := toStringSlice(t)
+		matched := false
+		for _, typ := range types {
+			if matchesSchemaType(typ, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return []string{fmt.Sprintf("%s: expected type %s", path, strings.Join(types, "|"))}
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, candidate := range enum {
+			if canonicalJSON(candidate) == canonicalJSON(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: value %s is not in enum", path, canonicalJSON(value)))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range toStringSlice(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if sub, ok := properties[key].(map[string]any); ok {
+				errs = append(errs, validateSchema(sub, v[key], path+"."+key)...)
+			} else if allowed, ok := schema["additionalProperties"].(bool); ok && !allowed {
+				errs = append(errs, fmt.Sprintf("%s: unexpected property %q", path, key))
+			}
+		}
+
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case float64:
+		if minimum, ok := toFloat(schema["minimum"]); ok && v < minimum {
+			errs = append(errs, fmt.Sprintf("%s: %v is less than minimum %v", path, v, minimum))
+		}
+		if maximum, ok := toFloat(schema["maximum"]); ok && v > maximum {
+			errs = append(errs, fmt.Sprintf("%s: %v is greater than maximum %v", path, v, maximum))
+		}
+	}
+
+	return errs
+}
+
+// matchesSchemaType reports whether a decoded JSON value matches a JSON Schema type name
+func matchesSchemaType(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return false
+	}
+}
+
+// toStringSlice converts a string or a list of strings from a decoded schema to []string
+func toStringSlice(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []any:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// toFloat converts a numeric schema keyword to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
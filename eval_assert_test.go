@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestAssertionCheck(t *testing.T) {
+	weatherTool := &ToolMeta{
+		Name: "get_weather",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"city":  map[string]any{"type": "string"},
+				"temp":  map[string]any{"type": "number", "minimum": -100, "maximum": 100},
+				"units": map[string]any{"type": "string", "enum": []any{"C", "F"}},
+			},
+			"required": []string{"city", "temp"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		assertion   EvalAssertion
+		response    string
+		toolMeta    *ToolMeta
+		expectFail  bool
+		expectError bool
+	}{
+		{
+			name:      "Contains passes",
+			assertion: EvalAssertion{Type: AssertContains, Value: "world"},
+			response:  "hello world",
+		},
+		{
+			name:       "Contains fails",
+			assertion:  EvalAssertion{Type: AssertContains, Value: "mars"},
+			response:   "hello world",
+			expectFail: true,
+		},
+		{
+			name:        "Contains requires string",
+			assertion:   EvalAssertion{Type: AssertContains, Value: 42.0},
+			response:    "42",
+			expectError: true,
+		},
+		{
+			name:      "Regex passes",
+			assertion: EvalAssertion{Type: AssertRegex, Value: `(?i)^yes\b`},
+			response:  "Yes, it is compatible",
+		},
+		{
+			name:       "Regex fails",
+			assertion:  EvalAssertion{Type: AssertRegex, Value: `^no`},
+			response:   "yes",
+			expectFail: true,
+		},
+		{
+			name:        "Invalid regex",
+			assertion:   EvalAssertion{Type: AssertRegex, Value: `(`},
+			response:    "yes",
+			expectError: true,
+		},
+		{
+			name:      "JSON field equals string",
+			assertion: EvalAssertion{Type: AssertJSONField, Field: "review.severity", Value: "high"},
+			response:  `{"review": {"severity": "high"}}`,
+		},
+		{
+			name:      "JSON field equals number in array",
+			assertion: EvalAssertion{Type: AssertJSONField, Field: "items.1.count", Value: 2.0},
+			response:  `{"items": [{"count": 1}, {"count": 2}]}`,
+		},
+		{
+			name:       "JSON field differs",
+			assertion:  EvalAssertion{Type: AssertJSONField, Field: "ok", Value: true},
+			response:   `{"ok": false}`,
+			expectFail: true,
+		},
+		{
+			name:       "JSON field missing",
+			assertion:  EvalAssertion{Type: AssertJSONField, Field: "missing", Value: "x"},
+			response:   `{}`,
+			expectFail: true,
+		},
+		{
+			name:      "Schema valid against tool schema",
+			assertion: EvalAssertion{Type: AssertSchemaValid},
+			response:  `{"city": "Taipei", "temp": 30, "units": "C"}`,
+			toolMeta:  weatherTool,
+		},
+		{
+			name:       "Schema missing required field",
+			assertion:  EvalAssertion{Type: AssertSchemaValid},
+			response:   `{"city": "Taipei"}`,
+			toolMeta:   weatherTool,
+			expectFail: true,
+		},
+		{
+			name:       "Schema enum and maximum violations",
+			assertion:  EvalAssertion{Type: AssertSchemaValid},
+			response:   `{"city": "Taipei", "temp": 300, "units": "K"}`,
+			toolMeta:   weatherTool,
+			expectFail: true,
+		},
+		{
+			name: "Inline schema",
+			assertion: EvalAssertion{Type: AssertSchemaValid, Schema: map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "integer"},
+			}},
+			response:   `[1, 2.5]`,
+			expectFail: true,
+		},
+		{
+			name:       "Schema with invalid JSON response",
+			assertion:  EvalAssertion{Type: AssertSchemaValid},
+			response:   `not json`,
+			toolMeta:   weatherTool,
+			expectFail: true,
+		},
+		{
+			name:        "Schema without any schema",
+			assertion:   EvalAssertion{Type: AssertSchemaValid},
+			response:    `{}`,
+			expectError: true,
+		},
+		{
+			name:        "Judge score without rubric",
+			assertion:   EvalAssertion{Type: AssertJudgeScore},
+			response:    "text",
+			expectError: true,
+		},
+		{
+			name:        "Unknown type",
+			assertion:   EvalAssertion{Type: "equals"},
+			response:    "text",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &assertionEnv{config: &Config{}, toolMeta: tt.toolMeta}
+			failure, _, err := env.check(context.Background(), tt.assertion, tt.response)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectFail && failure == "" {
+				t.Error("expected assertion to fail")
+			}
+			if !tt.expectFail && failure != "" {
+				t.Errorf("expected assertion to pass, got: %s", failure)
+			}
+		})
+	}
+}
+
+func TestAssertionCheckJudgeScore(t *testing.T) {
+	server := newChatCompletionServer(t, `{"score": 6, "pass": true, "reasons": ["ok"]}`, nil)
+	defer server.Close()
+
+	config := &Config{BaseURL: server.URL, APIKey: "test-key", JudgeRubric: "Be helpful"}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	env := &assertionEnv{judgeClient: client, config: config}
+
+	tests := []struct {
+		name       string
+		minScore   float64
+		expectFail bool
+	}{
+		{"Above minimum", 5, false},
+		{"Below minimum", 7, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minScore := tt.minScore
+			failure, usage, err := env.check(
+				context.Background(),
+				EvalAssertion{Type: AssertJudgeScore, MinScore: &minScore},
+				"response",
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (failure != "") != tt.expectFail {
+				t.Errorf("expected fail=%v, got %q", tt.expectFail, failure)
+			}
+			if usage.TotalTokens != 15 {
+				t.Errorf("expected judge usage of 15 tokens, got %d", usage.TotalTokens)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]any{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"count": {"type": ["integer", "null"]}
+		},
+		"required": ["tags"],
+		"additionalProperties": false
+	}`), &schema); err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{
+			name:  "Valid document",
+			value: `{"tags": ["a", "b"], "count": null}`,
+		},
+		{
+			name:     "Wrong item type",
+			value:    `{"tags": ["a", 1]}`,
+			expected: []string{"$.tags[1]: expected type string"},
+		},
+		{
+			name:     "Unexpected property and missing required",
+			value:    `{"extra": 1}`,
+			expected: []string{`$: missing required property "tags"`, `$: unexpected property "extra"`},
+		},
+		{
+			name:     "Wrong root type",
+			value:    `[]`,
+			expected: []string{"$: expected type object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("failed to parse value: %v", err)
+			}
+
+			got := validateSchema(schema, value, "$")
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Ensure the judge assertion sends the original conversation to the judge
+func TestAssertionCheckJudgeScoreMessages(t *testing.T) {
+	var captured openai.ChatCompletionRequest
+	server := newChatCompletionServer(t, `{"score": 9, "pass": true, "reasons": []}`, &captured)
+	defer server.Close()
+
+	config := &Config{BaseURL: server.URL, APIKey: "test-key"}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	env := &assertionEnv{
+		judgeClient: client,
+		config:      config,
+		messages:    []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Summarize"}},
+	}
+
+	if _, _, err := env.check(
+		context.Background(),
+		EvalAssertion{Type: AssertJudgeScore, Rubric: "Must be short"},
+		"Short summary",
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(captured.Messages[0].Content, "Must be short") {
+		t.Errorf("expected assertion rubric in judge prompt, got %q", captured.Messages[0].Content)
+	}
+	if !strings.Contains(captured.Messages[1].Content, "Summarize") {
+		t.Errorf("expected original request in judge input, got %q", captured.Messages[1].Content)
+	}
+}
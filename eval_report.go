@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the results of one model
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of one dataset row
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitMessage describes a failure or an error
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// formatSeconds formats a duration as seconds for JUnit time attributes
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// buildJUnitReport converts evaluation results into a JUnit report with one suite per model
+func buildJUnitReport(results []EvalResult, models []string, elapsed time.Duration) junitTestSuites {
+	report := junitTestSuites{
+		Name: ActionShortName + " eval",
+		Time: formatSeconds(elapsed),
+	}
+
+	for _, model := range models {
+		suite := junitTestSuite{Name: model}
+		var total time.Duration
+
+		for _, r := range results {
+			if r.Model != model {
+				continue
+			}
+			tc := junitTestCase{
+				Name:      r.Case,
+				ClassName: model,
+				Time:      formatSeconds(r.Duration),
+				SystemOut: r.Response,
+			}
+			switch {
+			case r.Error != "":
+				tc.Error = &junitMessage{Message: r.Error, Text: r.Error}
+				suite.Errors++
+			case len(r.Failures) > 0:
+				tc.Failure = &junitMessage{
+					Message: r.Failures[0],
+					Text:    strings.Join(r.Failures, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.Tests++
+			total += r.Duration
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suite.Time = formatSeconds(total)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	return report
+}
+
+// writeJUnitReport writes evaluation results as a JUnit XML file
+func writeJUnitReport(path string, results []EvalResult, models []string, elapsed time.Duration) error {
+	data, err := xml.MarshalIndent(buildJUnitReport(results, models, elapsed), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal junit report: %w", err)
+	}
+
+	content := append([]byte(xml.Header), data...)
+	if err := os.WriteFile(path, append(content, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write junit report: %w", err)
+	}
+	return nil
+}
+
+// renderMarkdownReport renders evaluation results as a Markdown summary
+func renderMarkdownReport(results []EvalResult, models []string) string {
+	var b strings.Builder
+
+	b.WriteString("## LLM Evaluation Report\n\n")
+	b.WriteString("| Model | Passed | Failed | Errors | Total Tokens |\n")
+	b.WriteString("| ----- | ------ | ------ | ------ | ------------ |\n")
+	for _, model := range models {
+		var total, passed, failed, errored, tokens int
+		for _, r := range results {
+			if r.Model != model {
+				continue
+			}
+			total++
+			tokens += r.Usage.TotalTokens
+			switch {
+			case r.Error != "":
+				errored++
+			case len(r.Failures) > 0:
+				failed++
+			default:
+				passed++
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %d/%d | %d | %d | %d |\n",
+			escapeMarkdownCell(model), passed, total, failed, errored, tokens)
+	}
+
+	b.WriteString("\n### Results\n\n")
+	b.WriteString("| Case | Model | Status | Duration | Details |\n")
+	b.WriteString("| ---- | ----- | ------ | -------- | ------- |\n")
+	for _, r := range results {
+		status, details := "✅ pass", ""
+		switch {
+		case r.Error != "":
+			status, details = "⚠️ error", r.Error
+		case len(r.Failures) > 0:
+			status, details = "❌ fail", strings.Join(r.Failures, "; ")
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			escapeMarkdownCell(r.Case),
+			escapeMarkdownCell(r.Model),
+			status,
+			r.Duration.Round(time.Millisecond),
+			escapeMarkdownCell(details),
+		)
+	}
+
+	return b.String()
+}
+
+// escapeMarkdownCell makes a value safe to place inside a Markdown table cell
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", " ")
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleEvalResults() []EvalResult {
+	return []EvalResult{
+		{Case: "alice", Model: "gpt-4o", Response: "Hello Alice", Duration: 1500 * time.Millisecond},
+		{Case: "bob", Model: "gpt-4o", Failures: []string{"response does not contain \"Bob\"", "second"}},
+		{Case: "alice", Model: "gpt-4o-mini", Error: "chat completion error: boom"},
+		{Case: "bob", Model: "gpt-4o-mini", Failures: []string{"a | b\nc"}},
+	}
+}
+
+func TestBuildJUnitReport(t *testing.T) {
+	report := buildJUnitReport(sampleEvalResults(), []string{"gpt-4o", "gpt-4o-mini"}, 2*time.Second)
+
+	if report.Tests != 4 || report.Failures != 2 || report.Errors != 1 {
+		t.Errorf("unexpected totals: tests=%d failures=%d errors=%d", report.Tests, report.Failures, report.Errors)
+	}
+	if report.Time != "2.000" {
+		t.Errorf("expected time 2.000, got %s", report.Time)
+	}
+	if len(report.Suites) != 2 {
+		t.Fatalf("expected 2 suites, got %d", len(report.Suites))
+	}
+
+	first := report.Suites[0]
+	if first.Name != "gpt-4o" || first.Tests != 2 || first.Failures != 1 || first.Errors != 0 {
+		t.Errorf("unexpected first suite: %+v", first)
+	}
+	if first.Cases[0].Time != "1.500" || first.Cases[0].SystemOut != "Hello Alice" {
+		t.Errorf("unexpected first case: %+v", first.Cases[0])
+	}
+	if first.Cases[1].Failure == nil || first.Cases[1].Failure.Text != "response does not contain \"Bob\"\nsecond" {
+		t.Errorf("unexpected failure: %+v", first.Cases[1].Failure)
+	}
+	if report.Suites[1].Cases[0].Error == nil {
+		t.Error("expected error element for errored case")
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := writeJUnitReport(path, sampleEvalResults(), []string{"gpt-4o", "gpt-4o-mini"}, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Error("expected XML header")
+	}
+
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("report is not valid XML: %v", err)
+	}
+	if parsed.Tests != 4 {
+		t.Errorf("expected 4 tests, got %d", parsed.Tests)
+	}
+}
+
+func TestRenderMarkdownReport(t *testing.T) {
+	report := renderMarkdownReport(sampleEvalResults(), []string{"gpt-4o", "gpt-4o-mini"})
+
+	expected := []string{
+		"## LLM Evaluation Report",
+		"| gpt-4o | 1/2 | 1 | 0 | 0 |",
+		"| gpt-4o-mini | 0/2 | 1 | 1 | 0 |",
+		"| alice | gpt-4o | ✅ pass | 1.5s |  |",
+		"| alice | gpt-4o-mini | ⚠️ error |",
+		`a \| b c`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(report, s) {
+			t.Errorf("expected report to contain %q, got:\n%s", s, report)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestLoadEvalDataset(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		content     string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name: "Rows with and without names",
+			content: `{"name": "greeting", "vars": {"NAME": "Alice"}, "assert": [{"type": "contains", "value": "Alice"}]}
+
+{"vars": {"NAME": "Bob"}}
+`,
+			expected: []string{"greeting", "case-3"},
+		},
+		{
+			name:        "Invalid JSON",
+			content:     "{not json}\n",
+			expectError: true,
+		},
+		{
+			name:        "Empty dataset",
+			content:     "\n\n",
+			expectError: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, "dataset"+string(rune('a'+i))+".jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write dataset: %v", err)
+			}
+
+			cases, err := loadEvalDataset(path)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			names := make([]string, 0, len(cases))
+			for _, c := range cases {
+				names = append(names, c.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected cases %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestRenderEvalConfig(t *testing.T) {
+	os.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+
+	base := &Config{
+		Model:        "gpt-4o",
+		SystemPrompt: "Reviewing {{.GITHUB_REPOSITORY}}",
+		InputPrompt:  "Say hello to {{.NAME}}",
+	}
+	c := EvalCase{Vars: map[string]any{"NAME": "Alice"}}
+
+	got, err := renderEvalConfig(base, c, "gpt-4o-mini")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Model != "gpt-4o-mini" {
+		t.Errorf("expected model gpt-4o-mini, got %s", got.Model)
+	}
+	if got.SystemPrompt != "Reviewing owner/repo" {
+		t.Errorf("unexpected system prompt: %q", got.SystemPrompt)
+	}
+	if got.InputPrompt != "Say hello to Alice" {
+		t.Errorf("unexpected input prompt: %q", got.InputPrompt)
+	}
+	// The base config keeps the raw template for the next row
+	if base.InputPrompt != "Say hello to {{.NAME}}" {
+		t.Errorf("base config was modified: %q", base.InputPrompt)
+	}
+}
+
+func TestRenderEvalConfigTemplateDisabled(t *testing.T) {
+	base := &Config{
+		SystemPrompt:     "Output {{ .Values }} verbatim",
+		InputPrompt:      "Say hello to {{.NAME}}",
+		TemplateDisabled: map[string]bool{"system_prompt": true},
+	}
+	c := EvalCase{Vars: map[string]any{"NAME": "Alice"}}
+
+	got, err := renderEvalConfig(base, c, "gpt-4o")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.SystemPrompt != "Output {{ .Values }} verbatim" {
+		t.Errorf("expected system prompt to stay unrendered, got %q", got.SystemPrompt)
+	}
+	if got.InputPrompt != "Say hello to Alice" {
+		t.Errorf("unexpected input prompt: %q", got.InputPrompt)
+	}
+}
+
+func TestLoadEvalConfig(t *testing.T) {
+	os.Setenv("INPUT_API_KEY", "test-key")
+	os.Setenv("INPUT_MODEL", "gpt-4o")
+	os.Setenv("INPUT_INPUT_PROMPT", "Hello {{.NAME}}")
+	defer func() {
+		os.Unsetenv("INPUT_API_KEY")
+		os.Unsetenv("INPUT_MODEL")
+		os.Unsetenv("INPUT_INPUT_PROMPT")
+	}()
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := loadEvalConfig([]string{"-dataset", "cases.jsonl"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Models) != 1 || cfg.Models[0] != "gpt-4o" {
+			t.Errorf("expected default model gpt-4o, got %v", cfg.Models)
+		}
+		if cfg.Concurrency != 4 {
+			t.Errorf("expected default concurrency 4, got %d", cfg.Concurrency)
+		}
+		// Prompts stay unrendered until each row is evaluated
+		if cfg.Base.InputPrompt != "Hello {{.NAME}}" {
+			t.Errorf("expected raw input prompt template, got %q", cfg.Base.InputPrompt)
+		}
+	})
+
+	t.Run("Multiple models", func(t *testing.T) {
+		cfg, err := loadEvalConfig([]string{
+			"-dataset", "cases.jsonl", "-models", "gpt-4o, gpt-4o-mini,", "-concurrency", "2",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(cfg.Models, ",") != "gpt-4o,gpt-4o-mini" {
+			t.Errorf("unexpected models: %v", cfg.Models)
+		}
+		if cfg.Concurrency != 2 {
+			t.Errorf("expected concurrency 2, got %d", cfg.Concurrency)
+		}
+	})
+
+	t.Run("Missing dataset", func(t *testing.T) {
+		if _, err := loadEvalConfig(nil); err != errEvalDatasetRequired {
+			t.Errorf("expected errEvalDatasetRequired, got %v", err)
+		}
+	})
+
+	t.Run("Invalid concurrency", func(t *testing.T) {
+		if _, err := loadEvalConfig([]string{"-dataset", "cases.jsonl", "-concurrency", "0"}); err == nil {
+			t.Error("expected error but got none")
+		}
+	})
+}
+
+// newEchoServer starts a test server that replies with the model name and the last message
+func newEchoServer(t *testing.T, inFlight, maxInFlight *int32) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inFlight != nil {
+			current := atomic.AddInt32(inFlight, 1)
+			defer atomic.AddInt32(inFlight, -1)
+			for {
+				seen := atomic.LoadInt32(maxInFlight)
+				if current <= seen || atomic.CompareAndSwapInt32(maxInFlight, seen, current) {
+					break
+				}
+			}
+		}
+
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		last := req.Messages[len(req.Messages)-1].Content
+		resp := openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleAssistant,
+					Content: req.Model + ": " + last,
+				}},
+			},
+			Usage: openai.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestRunEvalCases(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := newEchoServer(t, &inFlight, &maxInFlight)
+	defer server.Close()
+
+	base := &Config{
+		BaseURL:     server.URL,
+		APIKey:      "test-key",
+		InputPrompt: "Hello {{.NAME}}",
+	}
+	client, err := NewClient(base)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	cases := []EvalCase{
+		{
+			Name: "alice",
+			Vars: map[string]any{"NAME": "Alice"},
+			Assert: []EvalAssertion{
+				{Type: AssertContains, Value: "Hello Alice"},
+				{Type: AssertRegex, Value: `^gpt-4o`},
+			},
+		},
+		{
+			Name:   "bob",
+			Vars:   map[string]any{"NAME": "Bob"},
+			Assert: []EvalAssertion{{Type: AssertContains, Value: "Alice"}},
+		},
+		{
+			Name:   "bad assertion",
+			Vars:   map[string]any{"NAME": "Carol"},
+			Assert: []EvalAssertion{{Type: "unknown"}},
+		},
+	}
+	models := []string{"gpt-4o", "gpt-4o-mini"}
+
+	runner := &evalRunner{client: client, judgeClient: client, base: base}
+	results := runEvalCases(context.Background(), runner, cases, models, 2)
+
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(results))
+	}
+	if maxInFlight > 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", maxInFlight)
+	}
+
+	for i, r := range results {
+		expectedModel := models[i/len(cases)]
+		expectedCase := cases[i%len(cases)].Name
+		if r.Model != expectedModel || r.Case != expectedCase {
+			t.Errorf("result %d: expected %s/%s, got %s/%s", i, expectedModel, expectedCase, r.Model, r.Case)
+		}
+		if r.Usage.TotalTokens != 5 {
+			t.Errorf("result %d: expected 5 tokens, got %d", i, r.Usage.TotalTokens)
+		}
+	}
+
+	if !results[0].Passed() {
+		t.Errorf("expected alice to pass, got failures %v error %q", results[0].Failures, results[0].Error)
+	}
+	if results[0].Response != "gpt-4o: Hello Alice" {
+		t.Errorf("unexpected response: %q", results[0].Response)
+	}
+	if results[1].Passed() || len(results[1].Failures) != 1 {
+		t.Errorf("expected bob to fail one assertion, got %v", results[1].Failures)
+	}
+	if results[2].Error == "" {
+		t.Error("expected an error for the unknown assertion type")
+	}
+}
+
+func TestRunCaseDuration(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	echo := newEchoServer(t, nil, nil)
+	defer echo.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		echo.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	base := &Config{BaseURL: server.URL, APIKey: "test-key", InputPrompt: "Hello"}
+	client, err := NewClient(base)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	runner := &evalRunner{client: client, judgeClient: client, base: base}
+	result := runner.runCase(context.Background(), EvalCase{Name: "slow"}, "gpt-4o")
+	if result.Error != "" {
+		t.Fatalf("runCase() unexpected error: %s", result.Error)
+	}
+	if result.Duration < delay {
+		t.Errorf("Duration = %v, want at least %v", result.Duration, delay)
+	}
+}
+
+func TestRunEvalWithJSONLogs(t *testing.T) {
+	server := newEchoServer(t, nil, nil)
+	defer server.Close()
+
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "cases.jsonl")
+	content := `{"name": "alice", "vars": {"NAME": "Alice"}, "assert": [{"type": "contains", "value": "Alice"}]}`
+	if err := os.WriteFile(dataset, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	summary := filepath.Join(dir, "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+	t.Setenv("INPUT_BASE_URL", server.URL)
+	t.Setenv("INPUT_API_KEY", "test-key")
+	t.Setenv("INPUT_MODEL", "gpt-4o")
+	t.Setenv("INPUT_INPUT_PROMPT", "Hello {{.NAME}}")
+	buf := captureJSONLogs(t)
+
+	// Nothing but log events may reach stdout
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	err = execute([]string{"eval", "-dataset", dataset})
+	os.Stdout = stdout
+	_ = w.Close()
+	printed, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("eval unexpected error: %v", err)
+	}
+	if len(printed) > 0 {
+		t.Errorf("eval printed %q to stdout, want only JSON log events", printed)
+	}
+
+	events := parseLogEvents(t, buf.Bytes())
+	if result := events["eval_result"]; result["case"] != "alice" || result["passed"] != true {
+		t.Errorf("eval_result event = %v", result)
+	}
+	if finished := events["eval_finished"]; finished["passed"] != float64(1) || finished["failed"] != float64(0) {
+		t.Errorf("eval_finished event = %v", finished)
+	}
+	report, err := os.ReadFile(summary)
+	if err != nil || !strings.Contains(string(report), "alice") {
+		t.Errorf("step summary = %q, %v, want the Markdown report", report, err)
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for fetching prompts and other content from URLs
+const (
+	defaultFetchTimeout  = 30 * time.Second
+	defaultFetchMaxBytes = 10 * 1024 * 1024
+	defaultFetchRetries  = 2
+)
+
+// fetchRetryDelay is the base delay between retries, doubled after every attempt
+var fetchRetryDelay = time.Second
+
+// fetcherCache holds fetchers keyed by their inputs so CA certificates are loaded once
+var fetcherCache loadCache[*fetcher]
+
+// fetchInputs are the environment variables that configure URL fetching
+var fetchInputs = []string{
+	"INPUT_CA_CERT",
+	"INPUT_SKIP_SSL_VERIFY",
+	"INPUT_FETCH_TIMEOUT",
+	"INPUT_FETCH_MAX_BYTES",
+	"INPUT_FETCH_RETRIES",
+	"INPUT_FETCH_ALLOWED_HOSTS",
+	"INPUT_FETCH_AUTH",
+	"INPUT_FETCH_HEADERS",
+}
+
+// hostHeader is a header sent to hosts matching a pattern
+type hostHeader struct {
+	pattern string
+	key     string
+	value   string
+}
+
+// fetcher downloads remote content with the action's TLS settings, a size cap,
+// retries on transient errors, per-host headers and an optional host allowlist
+type fetcher struct {
+	client       *http.Client
+	maxBytes     int64
+	retries      int
+	allowedHosts []string
+	headers      []hostHeader
+
+	timeout       time.Duration
+	skipSSLVerify bool
+}
+
+// loadFetcher returns the fetcher configured by the current inputs
+func loadFetcher() (*fetcher, error) {
+	values := make([]string, len(fetchInputs))
+	for i, name := range fetchInputs {
+		values[i] = os.Getenv(name)
+	}
+	return fetcherCache.get(strings.Join(values, "\x00"), func(string) (*fetcher, error) {
+		return newFetcher()
+	})
+}
+
+// newFetcher builds a fetcher from the fetch_*, ca_cert and skip_ssl_verify inputs
+func newFetcher() (*fetcher, error) {
+	f, err := newBaseFetcher()
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := f.resolveCACert(os.Getenv("INPUT_CA_CERT"))
+	if err != nil {
+		return nil, err
+	}
+	if caCert != "" {
+		if f.client, err = f.newClient(caCert); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// newBaseFetcher builds a fetcher that trusts the system roots, ignoring ca_cert
+func newBaseFetcher() (*fetcher, error) {
+	config := &Config{}
+	if err := config.parseSkipSSL(os.Getenv("INPUT_SKIP_SSL_VERIFY")); err != nil {
+		return nil, err
+	}
+
+	timeout := defaultFetchTimeout
+	if s := os.Getenv("INPUT_FETCH_TIMEOUT"); s != "" {
+		var err error
+		if timeout, err = parsePositiveDuration("fetch_timeout", s); err != nil {
+			return nil, err
+		}
+	}
+
+	maxBytes, err := parseByteLimit("fetch_max_bytes", os.Getenv("INPUT_FETCH_MAX_BYTES"), defaultFetchMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := defaultFetchRetries
+	if s := os.Getenv("INPUT_FETCH_RETRIES"); s != "" {
+		if retries, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("invalid fetch_retries value: %w", err)
+		}
+		if retries < 0 {
+			return nil, fmt.Errorf("fetch_retries must not be negative")
+		}
+	}
+
+	headers, err := parseFetchAuth(os.Getenv("INPUT_FETCH_AUTH"))
+	if err != nil {
+		return nil, err
+	}
+	extra, err := parseFetchHeaders(os.Getenv("INPUT_FETCH_HEADERS"))
+	if err != nil {
+		return nil, err
+	}
+
+	f := &fetcher{
+		maxBytes:      maxBytes,
+		retries:       retries,
+		allowedHosts:  parseHostList(os.Getenv("INPUT_FETCH_ALLOWED_HOSTS")),
+		headers:       append(headers, extra...),
+		timeout:       timeout,
+		skipSSLVerify: config.SkipSSLVerify,
+	}
+	if f.client, err = f.newClient(""); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// newClient creates an HTTP client sharing the TLS settings and default headers of the
+// LLM client. Redirects are checked against the host allowlist and get the headers of
+// their own host.
+func (f *fetcher) newClient(caCert string) (*http.Client, error) {
+	client, err := createHTTPClient(caCert, f.skipSSLVerify, nil)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = f.timeout
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if err := f.checkHost(req); err != nil {
+			return err
+		}
+		f.applyHeaders(req)
+		return nil
+	}
+	return client, nil
+}
+
+// loadCACert resolves the ca_cert input, which can be content, a file path or a URL.
+// A certificate hosted at a URL is fetched with the system roots.
+func loadCACert(input string) (string, error) {
+	f, err := newBaseFetcher()
+	if err != nil {
+		return "", err
+	}
+	return f.resolveCACert(input)
+}
+
+// resolveCACert loads the ca_cert input with the fetcher
+func (f *fetcher) resolveCACert(input string) (string, error) {
+	switch {
+	case input == "":
+		return "", nil
+	case isURL(input):
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, input, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create request for URL %s: %w", input, err)
+		}
+		return f.fetch(req, input)
+	case isFilePath(input):
+		return loadFromFile(input)
+	default:
+		return input, nil
+	}
+}
+
+// parseHostList splits a comma or newline separated list of host patterns
+func parseHostList(s string) []string {
+	var hosts []string
+	for _, h := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// splitHostRule splits a "host=value" line of fetch_auth or fetch_headers
+func splitHostRule(name, line string) (string, string, error) {
+	host, value, found := strings.Cut(line, "=")
+	host = strings.ToLower(strings.TrimSpace(host))
+	if !found || host == "" {
+		return "", "", fmt.Errorf("invalid %s format: expected 'host=...'", name)
+	}
+	return host, strings.TrimSpace(value), nil
+}
+
+// parseFetchAuth parses newline separated "host=bearer TOKEN" or "host=basic USER:PASSWORD" lines
+func parseFetchAuth(s string) ([]hostHeader, error) {
+	var headers []hostHeader
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+
+		host, value, err := splitHostRule("fetch_auth", line)
+		if err != nil {
+			return nil, err
+		}
+
+		scheme, credentials, _ := strings.Cut(value, " ")
+		credentials = strings.TrimSpace(credentials)
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			if credentials == "" {
+				return nil, fmt.Errorf("invalid fetch_auth value for %s: missing token", host)
+			}
+			headers = append(headers, hostHeader{host, "Authorization", "Bearer " + credentials})
+		case "basic":
+			if !strings.Contains(credentials, ":") {
+				return nil, fmt.Errorf("invalid fetch_auth value for %s: expected 'basic USER:PASSWORD'", host)
+			}
+			encoded := base64.StdEncoding.EncodeToString([]byte(credentials))
+			headers = append(headers, hostHeader{host, "Authorization", "Basic " + encoded})
+		default:
+			return nil, fmt.Errorf("invalid fetch_auth value for %s: unsupported scheme %q (expected bearer or basic)", host, scheme)
+		}
+	}
+	return headers, nil
+}
+
+// parseFetchHeaders parses newline separated "host=Header: value" lines
+func parseFetchHeaders(s string) ([]hostHeader, error) {
+	var headers []hostHeader
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+
+		host, value, err := splitHostRule("fetch_headers", line)
+		if err != nil {
+			return nil, err
+		}
+
+		key, headerValue, found := strings.Cut(value, ":")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid fetch_headers value for %s: expected 'Header: value'", host)
+		}
+		headers = append(headers, hostHeader{host, key, strings.TrimSpace(headerValue)})
+	}
+	return headers, nil
+}
+
+// matchHost reports whether host matches a pattern such as "example.com" or "*.example.com"
+func matchHost(pattern, host string) bool {
+	ok, _ := path.Match(pattern, host)
+	return ok
+}
+
+// hostNotAllowedError is returned for hosts outside fetch_allowed_hosts
+type hostNotAllowedError struct {
+	host string
+}
+
+func (e *hostNotAllowedError) Error() string {
+	return fmt.Sprintf("host %s is not in fetch_allowed_hosts", e.host)
+}
+
+// checkHost rejects requests to hosts outside the allowlist
+func (f *fetcher) checkHost(req *http.Request) error {
+	if len(f.allowedHosts) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(req.URL.Hostname())
+	for _, pattern := range f.allowedHosts {
+		if matchHost(pattern, host) {
+			return nil
+		}
+	}
+	return &hostNotAllowedError{host}
+}
+
+// applyHeaders sets the fetch_auth and fetch_headers of the request host. http.Client
+// copies headers onto redirects, so headers of other hosts are removed again, and none
+// are sent over plain HTTP.
+func (f *fetcher) applyHeaders(req *http.Request) {
+	host := strings.ToLower(req.URL.Hostname())
+	secure := strings.EqualFold(req.URL.Scheme, "https")
+	for _, h := range f.headers {
+		if (!secure || !matchHost(h.pattern, host)) && req.Header.Get(h.key) == h.value {
+			req.Header.Del(h.key)
+		}
+	}
+
+	for _, h := range f.headers {
+		if !matchHost(h.pattern, host) {
+			continue
+		}
+		if !secure {
+			warnf("not sending fetch_auth or fetch_headers to %s over plain HTTP", host)
+			return
+		}
+		// Headers set by the caller, such as the GitHub token, take precedence
+		if req.Header.Get(h.key) == "" {
+			req.Header.Set(h.key, h.value)
+		}
+	}
+}
+
+// fetchContent sends a GET request with the fetcher configured by the current inputs
+func fetchContent(req *http.Request, source string) (string, error) {
+	f, err := loadFetcher()
+	if err != nil {
+		return "", err
+	}
+	return f.fetch(req, source)
+}
+
+// fetch sends a GET request and returns the response body, naming source in errors.
+// Network errors, 429 and 5xx responses are retried with exponential backoff.
+func (f *fetcher) fetch(req *http.Request, source string) (string, error) {
+	if err := f.checkHost(req); err != nil {
+		return "", fmt.Errorf("failed to fetch URL %s: %w", source, err)
+	}
+
+	f.applyHeaders(req)
+
+	var lastErr error
+	for attempt := 0; attempt <= f.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(fetchRetryDelay << (attempt - 1))
+		}
+
+		body, retry, err := f.fetchOnce(req, source)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+		if jsonLogger != nil {
+			jsonLogger.Warn("fetch_attempt_failed",
+				"url", redactURL(req.URL), "attempt", attempt+1, "retry", retry && attempt < f.retries,
+				"error", err.Error(), "error_class", errorClass(err))
+		}
+		if !retry {
+			break
+		}
+	}
+	return "", lastErr
+}
+
+// fetchOnce performs a single attempt, reporting whether a failure is worth retrying
+func (f *fetcher) fetchOnce(req *http.Request, source string) (string, bool, error) {
+	// Send request
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// Redirects to hosts outside the allowlist will not succeed on retry
+		var hostErr *hostNotAllowedError
+		return "", !errors.As(err, &hostErr), fmt.Errorf("failed to fetch URL %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	// Check status code
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return "", retry, fmt.Errorf("failed to fetch URL %s: status code %d", source, resp.StatusCode)
+	}
+
+	// Read response body, one byte past the limit to detect oversized responses
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read response from URL %s: %w", source, err)
+	}
+	if int64(len(body)) > f.maxBytes {
+		return "", false, fmt.Errorf(
+			"failed to fetch URL %s: response exceeds fetch_max_bytes (%d bytes)", source, f.maxBytes,
+		)
+	}
+
+	return string(body), false, nil
+}
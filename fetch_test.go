@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	// Keep retry tests fast
+	fetchRetryDelay = time.Millisecond
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   string
+		failures  int32
+		status    int
+		wantCalls int32
+		wantErr   string
+	}{
+		{name: "succeeds after transient errors", failures: 2, status: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "retries rate limits", failures: 1, status: http.StatusTooManyRequests, wantCalls: 2},
+		{
+			name: "gives up after retries", retries: "1", failures: 5, status: http.StatusBadGateway,
+			wantCalls: 2, wantErr: "status code 502",
+		},
+		{
+			name: "does not retry client errors", failures: 5, status: http.StatusNotFound,
+			wantCalls: 1, wantErr: "status code 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte("prompt"))
+			}))
+			defer server.Close()
+			t.Setenv("INPUT_FETCH_RETRIES", tt.retries)
+
+			got, err := LoadContent(server.URL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadContent() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil || got != "prompt" {
+				t.Errorf("LoadContent() = %q, %v, want %q", got, err, "prompt")
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFetchMaxBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	t.Setenv("INPUT_FETCH_MAX_BYTES", "100")
+	if got, err := LoadContent(server.URL); err != nil || len(got) != 100 {
+		t.Errorf("LoadContent() at the limit = %d bytes, %v", len(got), err)
+	}
+
+	t.Setenv("INPUT_FETCH_MAX_BYTES", "99")
+	_, err := LoadContent(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "exceeds fetch_max_bytes") {
+		t.Errorf("LoadContent() over the limit error = %v", err)
+	}
+}
+
+func TestFetchAllowedHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// Same server, but under a host name outside the allowlist
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/prompt", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("prompt"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		allowed string
+		path    string
+		wantErr bool
+	}{
+		{name: "no allowlist", path: "/prompt"},
+		{name: "exact host", allowed: "example.com, 127.0.0.1", path: "/prompt"},
+		{name: "wildcard host", allowed: "127.0.0.*", path: "/prompt"},
+		{name: "host not allowed", allowed: "example.com", path: "/prompt", wantErr: true},
+		{name: "redirect to host not allowed", allowed: "127.0.0.1", path: "/redirect", wantErr: true},
+		{name: "redirect to allowed host", allowed: "127.0.0.1\nlocalhost", path: "/redirect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_FETCH_ALLOWED_HOSTS", tt.allowed)
+
+			got, err := LoadContent(server.URL + tt.path)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "is not in fetch_allowed_hosts") {
+					t.Errorf("LoadContent() error = %v, want host not allowed", err)
+				}
+				return
+			}
+			if err != nil || got != "prompt" {
+				t.Errorf("LoadContent() = %q, %v, want %q", got, err, "prompt")
+			}
+		})
+	}
+}
+
+func TestFetchAuthAndHeaders(t *testing.T) {
+	var gotAuth, gotTeam, gotUserAgent string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotTeam, gotUserAgent = r.Header.Get("Authorization"), r.Header.Get("X-Team"), r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte("prompt"))
+	})
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+	t.Setenv("INPUT_SKIP_SSL_VERIFY", "true")
+
+	tests := []struct {
+		name     string
+		auth     string
+		headers  string
+		plain    bool
+		wantAuth string
+		wantTeam string
+	}{
+		{name: "no credentials"},
+		{name: "bearer token", auth: "127.0.0.1=bearer s3cr3t-token", wantAuth: "Bearer s3cr3t-token"},
+		{name: "basic auth", auth: "127.0.0.1=basic user:pass", wantAuth: "Basic dXNlcjpwYXNz"},
+		{name: "other host", auth: "prompts.example.com=bearer s3cr3t-token"},
+		{name: "custom header", headers: "127.0.0.*=X-Team: platform", wantTeam: "platform"},
+		{name: "plain HTTP", auth: "127.0.0.1=bearer s3cr3t-token", headers: "127.0.0.*=X-Team: platform", plain: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_FETCH_AUTH", tt.auth)
+			t.Setenv("INPUT_FETCH_HEADERS", tt.headers)
+
+			url := server.URL
+			if tt.plain {
+				url = plainServer.URL
+			}
+			if _, err := LoadContent(url); err != nil {
+				t.Fatalf("LoadContent() unexpected error: %v", err)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+			}
+			if gotTeam != tt.wantTeam {
+				t.Errorf("X-Team = %q, want %q", gotTeam, tt.wantTeam)
+			}
+			if gotUserAgent != GetUserAgent() {
+				t.Errorf("User-Agent = %q, want %q", gotUserAgent, GetUserAgent())
+			}
+		})
+	}
+}
+
+func TestFetchHeadersOnRedirect(t *testing.T) {
+	var gotKey, gotAuth string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// Same server, but under another host name
+			http.Redirect(w, r, "https://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/prompt", http.StatusFound)
+			return
+		}
+		gotKey, gotAuth = r.Header.Get("X-Api-Key"), r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("prompt"))
+	}))
+	defer server.Close()
+	t.Setenv("INPUT_SKIP_SSL_VERIFY", "true")
+
+	tests := []struct {
+		name     string
+		headers  string
+		auth     string
+		wantKey  string
+		wantAuth string
+	}{
+		{name: "other host", headers: "127.0.0.1=X-Api-Key: s3cret", auth: "127.0.0.1=bearer s3cr3t-token"},
+		{
+			name: "matching host", headers: "127.0.0.1=X-Api-Key: s3cret\nlocalhost=X-Api-Key: local",
+			auth: "localhost=bearer local-token", wantKey: "local", wantAuth: "Bearer local-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_FETCH_HEADERS", tt.headers)
+			t.Setenv("INPUT_FETCH_AUTH", tt.auth)
+
+			if _, err := LoadContent(server.URL + "/redirect"); err != nil {
+				t.Fatalf("LoadContent() unexpected error: %v", err)
+			}
+			if gotKey != tt.wantKey || gotAuth != tt.wantAuth {
+				t.Errorf("redirect target got X-Api-Key %q, Authorization %q, want %q, %q",
+					gotKey, gotAuth, tt.wantKey, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestFetchCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("prompt"))
+	}))
+	defer server.Close()
+
+	if _, err := LoadContent(server.URL); err == nil {
+		t.Fatal("LoadContent() expected certificate error without ca_cert")
+	}
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	t.Setenv("INPUT_CA_CERT", string(cert))
+	if got, err := LoadContent(server.URL); err != nil || got != "prompt" {
+		t.Errorf("LoadContent() with ca_cert = %q, %v", got, err)
+	}
+
+	t.Setenv("INPUT_CA_CERT", "")
+	t.Setenv("INPUT_SKIP_SSL_VERIFY", "true")
+	if got, err := LoadContent(server.URL); err != nil || got != "prompt" {
+		t.Errorf("LoadContent() with skip_ssl_verify = %q, %v", got, err)
+	}
+}
+
+func TestNewFetcherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		value   string
+		wantErr string
+	}{
+		{"invalid timeout", "INPUT_FETCH_TIMEOUT", "soon", "fetch_timeout"},
+		{"invalid max bytes", "INPUT_FETCH_MAX_BYTES", "lots", "fetch_max_bytes"},
+		{"invalid retries", "INPUT_FETCH_RETRIES", "many", "invalid fetch_retries value"},
+		{"negative retries", "INPUT_FETCH_RETRIES", "-1", "fetch_retries must not be negative"},
+		{"auth without host", "INPUT_FETCH_AUTH", "bearer token", "expected 'host=...'"},
+		{"auth unknown scheme", "INPUT_FETCH_AUTH", "example.com=digest abc", "unsupported scheme"},
+		{"auth missing token", "INPUT_FETCH_AUTH", "example.com=bearer", "missing token"},
+		{"basic without password", "INPUT_FETCH_AUTH", "example.com=basic user", "USER:PASSWORD"},
+		{"header without value", "INPUT_FETCH_HEADERS", "example.com=X-Team", "expected 'Header: value'"},
+		{"invalid ca cert", "INPUT_CA_CERT", "not a certificate", "failed to parse CA certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			_, err := newFetcher()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newFetcher() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// githubContextKeys lists the github context properties derived from GITHUB_* environment
+// variables. Each key maps to the variable named GITHUB_ followed by the upper-cased key,
+// e.g. "event_name" is read from GITHUB_EVENT_NAME.
+var githubContextKeys = []string{
+	"action",
+	"action_path",
+	"action_ref",
+	"action_repository",
+	"actor",
+	"actor_id",
+	"api_url",
+	"base_ref",
+	"event_name",
+	"event_path",
+	"graphql_url",
+	"head_ref",
+	"job",
+	"ref",
+	"ref_name",
+	"ref_type",
+	"repository",
+	"repository_id",
+	"repository_owner",
+	"repository_owner_id",
+	"retention_days",
+	"run_attempt",
+	"run_id",
+	"run_number",
+	"server_url",
+	"sha",
+	"triggering_actor",
+	"workflow",
+	"workflow_ref",
+	"workflow_sha",
+	"workspace",
+}
+
+// loadEventPayload parses the webhook payload at GITHUB_EVENT_PATH.
+// It returns nil when the path is unset or the file cannot be read or parsed,
+// so prompts still render outside of GitHub Actions.
+func loadEventPayload() map[string]any {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(content, &event); err != nil {
+		return nil
+	}
+	return event
+}
+
+// buildGitHubContext builds a template view mirroring the Actions github context,
+// e.g. {{.github.repository}} or {{.github.event.pull_request.title}}.
+// The token is deliberately not included.
+func buildGitHubContext(event map[string]any) map[string]any {
+	ctx := make(map[string]any, len(githubContextKeys)+2)
+	for _, key := range githubContextKeys {
+		ctx[key] = os.Getenv("GITHUB_" + strings.ToUpper(key))
+	}
+	ctx["ref_protected"] = os.Getenv("GITHUB_REF_PROTECTED") == "true"
+	ctx["event"] = event
+	return ctx
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPullRequestEvent is a trimmed pull_request webhook payload
+const testPullRequestEvent = `{
+  "action": "opened",
+  "number": 42,
+  "pull_request": {
+    "title": "Add template functions",
+    "body": "This adds helpers.",
+    "user": {"login": "octocat"},
+    "labels": [{"name": "enhancement"}, {"name": "templates"}]
+  }
+}`
+
+func TestLoadEventPayload(t *testing.T) {
+	tmpDir := t.TempDir()
+	validPath := filepath.Join(tmpDir, "event.json")
+	invalidPath := filepath.Join(tmpDir, "invalid.json")
+	if err := os.WriteFile(validPath, []byte(testPullRequestEvent), 0o600); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+	if err := os.WriteFile(invalidPath, []byte("{"), 0o600); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		expectNil bool
+	}{
+		{"Valid payload", validPath, false},
+		{"Unset path", "", true},
+		{"Missing file", filepath.Join(tmpDir, "missing.json"), true},
+		{"Invalid JSON", invalidPath, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("GITHUB_EVENT_PATH", tt.path)
+			defer os.Unsetenv("GITHUB_EVENT_PATH")
+
+			event := loadEventPayload()
+			if (event == nil) != tt.expectNil {
+				t.Errorf("expected nil=%v, got %v", tt.expectNil, event)
+			}
+			if event != nil && event["action"] != "opened" {
+				t.Errorf("expected action 'opened', got %v", event["action"])
+			}
+		})
+	}
+}
+
+func TestBuildGitHubContext(t *testing.T) {
+	envVars := map[string]string{
+		"GITHUB_REPOSITORY":    "owner/repo",
+		"GITHUB_EVENT_NAME":    "pull_request",
+		"GITHUB_RUN_ID":        "123456",
+		"GITHUB_REF_PROTECTED": "true",
+		"GITHUB_TOKEN":         "ghs_secret",
+	}
+	for key, value := range envVars {
+		os.Setenv(key, value)
+	}
+	defer func() {
+		for key := range envVars {
+			os.Unsetenv(key)
+		}
+	}()
+
+	event := map[string]any{"action": "opened"}
+	ctx := buildGitHubContext(event)
+
+	if ctx["repository"] != "owner/repo" {
+		t.Errorf("expected repository owner/repo, got %v", ctx["repository"])
+	}
+	if ctx["event_name"] != "pull_request" {
+		t.Errorf("expected event_name pull_request, got %v", ctx["event_name"])
+	}
+	if ctx["run_id"] != "123456" {
+		t.Errorf("expected run_id 123456, got %v", ctx["run_id"])
+	}
+	if ctx["ref_protected"] != true {
+		t.Errorf("expected ref_protected to be a true boolean, got %v", ctx["ref_protected"])
+	}
+	if ctx["event"].(map[string]any)["action"] != "opened" {
+		t.Errorf("expected event payload in context, got %v", ctx["event"])
+	}
+	if _, ok := ctx["token"]; ok {
+		t.Error("github context must not expose the token")
+	}
+}
+
+func TestRenderTemplateWithEvent(t *testing.T) {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(testPullRequestEvent), 0o600); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+
+	envVars := map[string]string{
+		"GITHUB_EVENT_PATH": eventPath,
+		"GITHUB_EVENT_NAME": "pull_request",
+		"GITHUB_REPOSITORY": "owner/repo",
+	}
+	for key, value := range envVars {
+		os.Setenv(key, value)
+	}
+	defer func() {
+		for key := range envVars {
+			os.Unsetenv(key)
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "event payload",
+			template: "{{.event.pull_request.title}} by {{.event.pull_request.user.login}}",
+			want:     "Add template functions by octocat",
+		},
+		{
+			name:     "range over labels",
+			template: "{{range .event.pull_request.labels}}[{{.name}}]{{end}}",
+			want:     "[enhancement][templates]",
+		},
+		{
+			name:     "github context",
+			template: "{{.github.event_name}} on {{.github.repository}}: {{.github.event.pull_request.body}}",
+			want:     "pull_request on owner/repo: This adds helpers.",
+		},
+		{
+			name:     "numbers keep their JSON form",
+			template: "PR #{{.event.number}}",
+			want:     "PR #42",
+		},
+		{
+			name:     "flat env keys keep working",
+			template: "{{.GITHUB_REPOSITORY}} {{.GITHUB_EVENT_NAME}}",
+			want:     "owner/repo pull_request",
+		},
+		{
+			name:     "missing event field",
+			template: "{{with .event.issue}}issue{{else}}no issue{{end}}",
+			want:     "no issue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("RenderTemplate() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// globFiles returns the regular files matching a glob pattern, sorted by path.
+// In addition to the filepath.Match syntax, "**" matches any number of directories,
+// so "src/**/*.go" matches Go files at any depth below src.
+func globFiles(pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return nil, nil
+	}
+
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	root := globRoot(pattern)
+	var matches []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// A missing root simply means there are no matches
+			if os.IsNotExist(err) && path == root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel := filepath.ToSlash(path)
+		if re.MatchString(rel) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand glob %q: %w", pattern, err)
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// globRoot returns the longest directory prefix of the pattern without glob metacharacters
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	var static []string
+	for _, segment := range segments[:len(segments)-1] {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		static = append(static, segment)
+	}
+
+	if len(static) == 0 {
+		return "."
+	}
+	root := strings.Join(static, "/")
+	if root == "" {
+		return "/"
+	}
+	return filepath.FromSlash(root)
+}
+
+// globToRegexp converts a slash separated glob pattern into an anchored regular expression
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	// Paths produced by filepath.WalkDir from "." have no "./" prefix
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid glob %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
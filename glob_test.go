@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlobFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := []string{
+		"main.go",
+		"README.md",
+		"src/a.go",
+		"src/a_test.go",
+		"src/pkg/b.go",
+		"src/pkg/deep/c.go",
+		"docs/guide.md",
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(f), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	root := filepath.ToSlash(tmpDir)
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "Single level",
+			pattern:  root + "/*.go",
+			expected: []string{"main.go"},
+		},
+		{
+			name:     "Double star",
+			pattern:  root + "/src/**/*.go",
+			expected: []string{"src/a.go", "src/a_test.go", "src/pkg/b.go", "src/pkg/deep/c.go"},
+		},
+		{
+			name:     "Double star from root",
+			pattern:  root + "/**/*.md",
+			expected: []string{"README.md", "docs/guide.md"},
+		},
+		{
+			name:     "Question mark and class",
+			pattern:  root + "/src/[ab].go",
+			expected: []string{"src/a.go"},
+		},
+		{
+			name:     "Negated class",
+			pattern:  root + "/src/pkg/[!a].go",
+			expected: []string{"src/pkg/b.go"},
+		},
+		{
+			name:     "Missing directory",
+			pattern:  root + "/missing/*.go",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := globFiles(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rel := make([]string, 0, len(got))
+			for _, p := range got {
+				r, err := filepath.Rel(tmpDir, p)
+				if err != nil {
+					t.Fatalf("failed to relativize %s: %v", p, err)
+				}
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			if strings.Join(rel, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, rel)
+			}
+		})
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"src/**", "src/a/b", true},
+		{"./src/*.go", "src/a.go", true},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"a+b.txt", "a+b.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.path, func(t *testing.T) {
+			re, err := globToRegexp(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if re.MatchString(tt.path) != tt.match {
+				t.Errorf("expected match=%v for %q against %q", tt.match, tt.path, tt.pattern)
+			}
+		})
+	}
+
+	if _, err := globToRegexp("[abc"); err == nil {
+		t.Error("expected error for unterminated character class")
+	}
+}
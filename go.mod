@@ -8,3 +8,5 @@ require (
 )
 
 require github.com/yassinebenaid/godump v0.11.1
+
+require go.yaml.in/yaml/v3 v3.0.4
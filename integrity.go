@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// integritySuffix matches a "#sha256-<digest>" pin at the end of an input. The digest
+// is hex encoded or base64 encoded like Subresource Integrity.
+var integritySuffix = regexp.MustCompile(`#sha256-([0-9A-Fa-f]{64}|[A-Za-z0-9+/_-]{43}=?)$`)
+
+// signatureExt is appended to a remote source to locate its detached signature
+const signatureExt = ".sig"
+
+// publicKeyCache holds parsed prompt_public_key values
+var publicKeyCache loadCache[crypto.PublicKey]
+
+// splitIntegrity splits an input into its source and an optional pinned sha256 digest
+func splitIntegrity(input string) (string, string) {
+	loc := integritySuffix.FindStringSubmatchIndex(input)
+	if loc == nil {
+		return input, ""
+	}
+	return input[:loc[0]], input[loc[2]:loc[3]]
+}
+
+// decodeDigest decodes a hex or base64 encoded sha256 digest
+func decodeDigest(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "sha256-")
+	if len(s) == hex.EncodedLen(sha256.Size) {
+		return hex.DecodeString(s)
+	}
+
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if digest, err := enc.DecodeString(s); err == nil && len(digest) == sha256.Size {
+			return digest, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid sha256 digest %q (expected 64 hex characters or base64)", s)
+}
+
+// verifyIntegrity checks that content matches the pinned sha256 digest
+func verifyIntegrity(source, content, pinned string) error {
+	want, err := decodeDigest(pinned)
+	if err != nil {
+		return err
+	}
+
+	got := sha256.Sum256([]byte(content))
+	if !bytes.Equal(got[:], want) {
+		return fmt.Errorf(
+			"integrity check failed for %s: expected sha256-%s, got sha256-%s",
+			describeSource(source), hex.EncodeToString(want), hex.EncodeToString(got[:]),
+		)
+	}
+	return nil
+}
+
+// describeSource names a source in errors without echoing inline content
+func describeSource(source string) string {
+	if isURL(source) || isGitHubSource(source) || isGitSource(source) || isEnvSource(source) ||
+		isStdinSource(source) || isFilePath(source) {
+		return source
+	}
+	return "inline content"
+}
+
+// pinLoader wraps a loader so its input is pinned to the digest of the companion
+// <name>_sha256 input, e.g. system_prompt_sha256
+func pinLoader(name string, load func(string) (string, error)) func(string) (string, error) {
+	digest := strings.TrimSpace(os.Getenv("INPUT_" + strings.ToUpper(name) + "_SHA256"))
+	if digest == "" {
+		return load
+	}
+
+	return func(input string) (string, error) {
+		want, err := decodeDigest(digest)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s_sha256 value: %w", name, err)
+		}
+		source, pinned := splitIntegrity(input)
+		if pinned != "" {
+			// Both pins must agree
+			if got, err := decodeDigest(pinned); err != nil || !bytes.Equal(got, want) {
+				return "", fmt.Errorf("%s_sha256 does not match the sha256 pinned in %s", name, source)
+			}
+		}
+		return load(source + "#sha256-" + hex.EncodeToString(want))
+	}
+}
+
+// isRemoteSource reports whether content is loaded over the network
+func isRemoteSource(source string) bool {
+	return isURL(source) || isGitHubSource(source)
+}
+
+// signatureSource returns the location of the detached signature of a remote source:
+// the same URL or repository path with ".sig" appended, keeping any query string or ref
+func signatureSource(source string) (string, error) {
+	if isGitHubSource(source) {
+		location, ref := splitRef(source)
+		if ref == "" {
+			return location + signatureExt, nil
+		}
+		return location + signatureExt + "@" + ref, nil
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s: %w", source, err)
+	}
+	u.Path += signatureExt
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// verifySignature checks the detached signature of remote content against the
+// prompt_public_key input. Without a key configured nothing is checked.
+func verifySignature(source, content string) error {
+	keyInput := os.Getenv("INPUT_PROMPT_PUBLIC_KEY")
+	if keyInput == "" || !isRemoteSource(source) {
+		return nil
+	}
+
+	key, err := loadPublicKey(keyInput)
+	if err != nil {
+		return err
+	}
+
+	sigSource, err := signatureSource(source)
+	if err != nil {
+		return err
+	}
+	encoded, err := loadSource(sigSource)
+	if err != nil {
+		return fmt.Errorf("failed to load signature for %s: %w", source, err)
+	}
+	// Signatures may be stored base64 encoded or as raw bytes
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		signature = []byte(encoded)
+	}
+
+	if !verifyWithKey(key, []byte(content), signature) {
+		return fmt.Errorf("signature verification failed for %s", source)
+	}
+	return nil
+}
+
+// verifyWithKey verifies an Ed25519 signature of the content, or an ECDSA or
+// RSA PKCS #1 v1.5 signature of its sha256 digest
+func verifyWithKey(key crypto.PublicKey, content, signature []byte) bool {
+	digest := sha256.Sum256(content)
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, content, signature)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+// loadPublicKey parses the prompt_public_key input, a PEM encoded public key given as
+// content or a file path. URLs are refused since the key must not come from the
+// same place as the content it protects.
+func loadPublicKey(input string) (crypto.PublicKey, error) {
+	return publicKeyCache.get(input, func(input string) (crypto.PublicKey, error) {
+		if isRemoteSource(input) || isGitSource(input) {
+			return nil, errors.New("invalid prompt_public_key value: must be PEM content or a file path")
+		}
+
+		content := input
+		if isFilePath(input) {
+			var err error
+			if content, err = loadFromFile(input); err != nil {
+				return nil, fmt.Errorf("failed to load prompt_public_key: %w", err)
+			}
+		}
+
+		block, _ := pem.Decode([]byte(content))
+		if block == nil {
+			return nil, errors.New("invalid prompt_public_key value: no PEM block found")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt_public_key value: %w", err)
+		}
+
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("invalid prompt_public_key value: unsupported key type %T", key)
+		}
+	})
+}
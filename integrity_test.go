@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPinnedContent = "You are a code reviewer for {{.GITHUB_REPOSITORY}}."
+
+func testDigests(content string) (string, string) {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:]), base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestSplitIntegrity(t *testing.T) {
+	hexDigest, b64Digest := testDigests("x")
+	tests := []struct {
+		input      string
+		wantSource string
+		wantPin    string
+	}{
+		{"https://example.com/p.md#sha256-" + hexDigest, "https://example.com/p.md", hexDigest},
+		{"github://org/prompts/p.md@v1#sha256-" + b64Digest, "github://org/prompts/p.md@v1", b64Digest},
+		{"prompts/p.md", "prompts/p.md", ""},
+		{"https://example.com/p.md#sha256-abc", "https://example.com/p.md#sha256-abc", ""},
+		{"Explain #sha256-hashes", "Explain #sha256-hashes", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			source, pin := splitIntegrity(tt.input)
+			if source != tt.wantSource || pin != tt.wantPin {
+				t.Errorf("splitIntegrity() = %q, %q, want %q, %q", source, pin, tt.wantSource, tt.wantPin)
+			}
+		})
+	}
+}
+
+func TestLoadContentIntegrity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testPinnedContent))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prompt.md")
+	if err := os.WriteFile(file, []byte(testPinnedContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hexDigest, b64Digest := testDigests(testPinnedContent)
+	otherDigest, _ := testDigests("tampered")
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "URL with hex digest", input: server.URL + "/prompt.md#sha256-" + hexDigest},
+		{name: "URL with base64 digest", input: server.URL + "/prompt.md#sha256-" + b64Digest},
+		{name: "file with digest", input: file + "#sha256-" + hexDigest},
+		{name: "unpinned URL", input: server.URL + "/prompt.md"},
+		{
+			name: "URL with wrong digest", input: server.URL + "/prompt.md#sha256-" + otherDigest,
+			wantErr: "integrity check failed for " + server.URL + "/prompt.md",
+		},
+		{
+			name: "inline content with wrong digest", input: "hello#sha256-" + otherDigest,
+			wantErr: "integrity check failed for inline content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadContent(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadContent() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadContent() unexpected error: %v", err)
+			}
+			if got != testPinnedContent {
+				t.Errorf("LoadContent() = %q, want %q", got, testPinnedContent)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithCompanionDigest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testPinnedContent))
+	}))
+	defer server.Close()
+
+	hexDigest, b64Digest := testDigests(testPinnedContent)
+	otherDigest, _ := testDigests("tampered")
+
+	tests := []struct {
+		name    string
+		prompt  string
+		digest  string
+		wantErr string
+	}{
+		{name: "matching digest", prompt: server.URL, digest: hexDigest},
+		{name: "matching digest with prefix", prompt: server.URL, digest: "sha256-" + b64Digest},
+		{name: "matching suffix and digest", prompt: server.URL + "#sha256-" + hexDigest, digest: b64Digest},
+		{name: "mismatched digest", prompt: server.URL, digest: otherDigest, wantErr: "integrity check failed"},
+		{
+			name: "conflicting suffix and digest", prompt: server.URL + "#sha256-" + otherDigest, digest: hexDigest,
+			wantErr: "system_prompt_sha256 does not match",
+		},
+		{name: "invalid digest", prompt: server.URL, digest: "abc", wantErr: "invalid system_prompt_sha256 value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_API_KEY", "test-key")
+			t.Setenv("INPUT_INPUT_PROMPT", "Hello")
+			t.Setenv("INPUT_SYSTEM_PROMPT", tt.prompt)
+			t.Setenv("INPUT_SYSTEM_PROMPT_SHA256", tt.digest)
+			t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+
+			config, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			// The digest covers the raw content, the template is rendered afterwards
+			if want := "You are a code reviewer for owner/repo."; config.SystemPrompt != want {
+				t.Errorf("SystemPrompt = %q, want %q", config.SystemPrompt, want)
+			}
+		})
+	}
+}
+
+func TestLoadContentSignature(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256([]byte(testPinnedContent))
+	edSig := ed25519.Sign(edPrivate, []byte(testPinnedContent))
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecPrivate, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaPrivate, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signatures := map[string][]byte{
+		"/ed25519.md.sig": []byte(base64.StdEncoding.EncodeToString(edSig) + "\n"),
+		"/ecdsa.md.sig":   ecSig,
+		"/rsa.md.sig":     []byte(base64.StdEncoding.EncodeToString(rsaSig)),
+		"/tampered.md.sig": []byte(base64.StdEncoding.EncodeToString(
+			ed25519.Sign(edPrivate, []byte("something else")),
+		)),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, signatureExt) {
+			sig, ok := signatures[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(sig)
+			return
+		}
+		_, _ = w.Write([]byte(testPinnedContent))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		key     crypto.PublicKey
+		path    string
+		wantErr string
+	}{
+		{name: "ed25519 signature", key: edPublic, path: "/ed25519.md"},
+		{name: "ecdsa raw signature", key: &ecPrivate.PublicKey, path: "/ecdsa.md"},
+		{name: "rsa signature", key: &rsaPrivate.PublicKey, path: "/rsa.md"},
+		{name: "query string is kept", key: edPublic, path: "/ed25519.md?ref=main"},
+		{name: "wrong key", key: &ecPrivate.PublicKey, path: "/ed25519.md", wantErr: "signature verification failed"},
+		{name: "tampered content", key: edPublic, path: "/tampered.md", wantErr: "signature verification failed"},
+		{name: "missing signature", key: edPublic, path: "/unsigned.md", wantErr: "failed to load signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			der, err := x509.MarshalPKIXPublicKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("INPUT_PROMPT_PUBLIC_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+
+			got, err := LoadContent(server.URL + tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadContent() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadContent() unexpected error: %v", err)
+			}
+			if got != testPinnedContent {
+				t.Errorf("LoadContent() = %q, want %q", got, testPinnedContent)
+			}
+		})
+	}
+
+	// Local and inline content is not signed
+	if got, err := LoadContent("inline prompt"); err != nil || got != "inline prompt" {
+		t.Errorf("LoadContent() inline = %q, %v", got, err)
+	}
+}
+
+func TestSignatureSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"https://example.com/prompts/review.md", "https://example.com/prompts/review.md.sig"},
+		{"https://example.com/review.md?token=abc", "https://example.com/review.md.sig?token=abc"},
+		{"github://org/prompts/review.md@v1.2.0", "github://org/prompts/review.md.sig@v1.2.0"},
+		{"github://org/prompts/review.md", "github://org/prompts/review.md.sig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := signatureSource(tt.source)
+			if err != nil || got != tt.want {
+				t.Errorf("signatureSource() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPublicKeyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"url", "https://example.com/key.pem", "must be PEM content or a file path"},
+		{"not pem", "not a key", "no PEM block found"},
+		{"bad key", "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----", "invalid prompt_public_key value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadPublicKey(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadPublicKey() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
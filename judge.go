@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// judgeMaxScore is the upper bound of the score scale used by the judge
+const judgeMaxScore = 10
+
+// judgeEvaluationTool is the internal function schema the judge model uses to report its verdict
+var judgeEvaluationTool = ToolMeta{
+	Name:        "submit_evaluation",
+	Description: "Submit the evaluation of the response against the rubric",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"score": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Quality score from 0 (worst) to %d (best)", judgeMaxScore),
+				"minimum":     0,
+				"maximum":     judgeMaxScore,
+			},
+			"pass": map[string]any{
+				"type":        "boolean",
+				"description": "Whether the response satisfies the rubric",
+			},
+			"reasons": map[string]any{
+				"type":        "array",
+				"description": "Concise reasons supporting the score",
+				"items":       map[string]any{"type": "string"},
+			},
+		},
+		"required": []string{"score", "pass", "reasons"},
+	},
+}
+
+const judgeEvaluationPrompt = `You are a strict and impartial evaluator.
+Evaluate the response to the request below against the following rubric and call the submit_evaluation function with your verdict.
+
+## Rubric
+
+%s`
+
+// JudgeResult is the structured verdict returned by the judge model
+type JudgeResult struct {
+	Score   float64  `json:"score"`
+	Pass    bool     `json:"pass"`
+	Reasons []string `json:"reasons"`
+}
+
+// judgeEnabled reports whether a judge evaluation is configured
+func (c *Config) judgeEnabled() bool {
+	return c.JudgeRubric != ""
+}
+
+// judgeSharesEndpoint reports whether judge calls go to the primary base URL, the only
+// case where the primary API key may be sent with them
+func (c *Config) judgeSharesEndpoint() bool {
+	return c.JudgeBaseURL == "" ||
+		strings.TrimSuffix(c.JudgeBaseURL, "/") == strings.TrimSuffix(c.BaseURL, "/")
+}
+
+// checkJudgeAPIKey requires a judge API key when judge calls go to another provider,
+// so the primary API key is never sent to a different host
+func (c *Config) checkJudgeAPIKey() error {
+	if c.JudgeAPIKey == "" && !c.judgeSharesEndpoint() {
+		return errJudgeAPIKeyRequired
+	}
+	return nil
+}
+
+// judgeConfig returns a copy of the configuration to be used for judge calls.
+// Judge specific model, base URL and API key override the primary ones when set,
+// and the judge always runs deterministically with a single completion. The primary
+// API key is only kept when the judge uses the primary base URL.
+func (c *Config) judgeConfig() *Config {
+	jc := *c
+	if c.JudgeModel != "" {
+		jc.Model = c.JudgeModel
+	}
+	if c.JudgeBaseURL != "" {
+		jc.BaseURL = c.JudgeBaseURL
+	}
+	if c.JudgeAPIKey != "" {
+		jc.APIKey = c.JudgeAPIKey
+	} else if !c.judgeSharesEndpoint() {
+		jc.APIKey = ""
+	}
+	jc.N = 1
+	jc.Temperature = 0
+	return &jc
+}
+
+// newJudgeClient returns the client used for judge calls.
+// The primary client is reused unless the judge targets a different endpoint or key.
+func newJudgeClient(config *Config, primary *openai.Client) (*openai.Client, error) {
+	if err := config.checkJudgeAPIKey(); err != nil {
+		return nil, err
+	}
+	if config.JudgeBaseURL == "" && config.JudgeAPIKey == "" {
+		return primary, nil
+	}
+
+	client, err := NewClient(config.judgeConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create judge client: %w", err)
+	}
+	return client, nil
+}
+
+// evaluateResponse asks the judge model to score the response against the rubric
+func evaluateResponse(
+	ctx context.Context,
+	client *openai.Client,
+	config *Config,
+	messages []openai.ChatCompletionMessage,
+	response string,
+) (*JudgeResult, openai.Usage, error) {
+	judgeMessages, err := prepareJudgeMessages(config, []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: fmt.Sprintf(judgeEvaluationPrompt, config.JudgeRubric),
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: buildJudgeEvaluationInput(messages, response),
+		},
+	})
+	if err != nil {
+		return nil, openai.Usage{}, err
+	}
+
+	judgeConfig := config.judgeConfig()
+	req := buildChatRequest(judgeConfig, judgeMessages, &judgeEvaluationTool)
+	start := time.Now()
+	resp, err := tracedChatCompletion(ctx, client, judgeConfig, req, auditKindJudgeEvaluation)
+	logCompletion(judgeConfig, auditKindJudgeEvaluation, resp, time.Since(start), err)
+	if auditErr := auditCompletion(config, auditKindJudgeEvaluation, req, resp, err); auditErr != nil {
+		return nil, openai.Usage{}, auditErr
+	}
+	if err != nil {
+		return nil, openai.Usage{}, fmt.Errorf("judge evaluation error: %w", err)
+	}
+
+	args, err := extractResponse(resp, &judgeEvaluationTool, config.Debug)
+	if err != nil {
+		return nil, resp.Usage, fmt.Errorf("judge evaluation: %w", err)
+	}
+
+	var result JudgeResult
+	if err := json.Unmarshal([]byte(args), &result); err != nil {
+		return nil, resp.Usage, fmt.Errorf("failed to parse judge evaluation: %w", err)
+	}
+	if result.Score < 0 || result.Score > judgeMaxScore {
+		return nil, resp.Usage, fmt.Errorf(
+			"judge score %v is out of range (0-%d)", result.Score, judgeMaxScore,
+		)
+	}
+
+	return &result, resp.Usage, nil
+}
+
+// prepareJudgeMessages redacts and scans judge messages like the messages of regular
+// requests. They carry the rendered judge_rubric and may go to another provider.
+func prepareJudgeMessages(
+	config *Config,
+	messages []openai.ChatCompletionMessage,
+) ([]openai.ChatCompletionMessage, error) {
+	redactor := newRedactor(config)
+	// The conversation is usually redacted already, keep its placeholders unambiguous
+	redactor.reservePlaceholders(messages)
+	messages = redactor.redactMessages(messages)
+	if err := scanMessagesForSecrets(config, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// buildJudgeEvaluationInput formats the original conversation and the response for the judge
+func buildJudgeEvaluationInput(messages []openai.ChatCompletionMessage, response string) string {
+	var b strings.Builder
+
+	b.WriteString("## Request\n\n")
+	writeConversation(&b, messages)
+
+	b.WriteString("## Response\n\n")
+	b.WriteString(response)
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// writeConversation writes each message prefixed with its role
+func writeConversation(b *strings.Builder, messages []openai.ChatCompletionMessage) {
+	for _, m := range messages {
+		fmt.Fprintf(b, "[%s]\n%s\n\n", m.Role, m.Content)
+	}
+}
+
+// meetsMinScore reports whether the judge result satisfies the configured minimum score
+func (r *JudgeResult) meetsMinScore(minScore float64) bool {
+	return r.Score >= minScore
+}
+
+// addJudgeResultToOutput adds the judge verdict to the output map
+func addJudgeResultToOutput(output map[string]string, result *JudgeResult) error {
+	reasons := result.Reasons
+	if reasons == nil {
+		reasons = []string{}
+	}
+	data, err := json.Marshal(reasons)
+	if err != nil {
+		return fmt.Errorf("failed to marshal judge reasons: %w", err)
+	}
+
+	output["judge_score"] = strconv.FormatFloat(result.Score, 'f', -1, 64)
+	output["judge_pass"] = strconv.FormatBool(result.Pass)
+	output["judge_reasons"] = string(data)
+	return nil
+}
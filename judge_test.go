@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestJudgeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		model   string
+		baseURL string
+		apiKey  string
+	}{
+		{
+			name: "Defaults to primary settings",
+			config: &Config{
+				Model:   "gpt-4o",
+				BaseURL: "https://api.openai.com/v1",
+				APIKey:  "primary-key",
+			},
+			model:   "gpt-4o",
+			baseURL: "https://api.openai.com/v1",
+			apiKey:  "primary-key",
+		},
+		{
+			name: "Judge overrides",
+			config: &Config{
+				Model:        "gpt-4o-mini",
+				BaseURL:      "https://api.openai.com/v1",
+				APIKey:       "primary-key",
+				JudgeModel:   "gpt-4o",
+				JudgeBaseURL: "http://localhost:11434/v1",
+				JudgeAPIKey:  "judge-key",
+			},
+			model:   "gpt-4o",
+			baseURL: "http://localhost:11434/v1",
+			apiKey:  "judge-key",
+		},
+		{
+			name: "Same base URL keeps the primary key",
+			config: &Config{
+				Model:        "gpt-4o",
+				BaseURL:      "https://api.openai.com/v1",
+				APIKey:       "primary-key",
+				JudgeBaseURL: "https://api.openai.com/v1/",
+			},
+			model:   "gpt-4o",
+			baseURL: "https://api.openai.com/v1/",
+			apiKey:  "primary-key",
+		},
+		{
+			name: "Other base URL never gets the primary key",
+			config: &Config{
+				Model:        "gpt-4o",
+				BaseURL:      "https://api.openai.com/v1",
+				APIKey:       "primary-key",
+				JudgeBaseURL: "https://judge.example.com/v1",
+			},
+			model:   "gpt-4o",
+			baseURL: "https://judge.example.com/v1",
+			apiKey:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.N = 5
+			tt.config.Temperature = 0.9
+
+			jc := tt.config.judgeConfig()
+			if jc.Model != tt.model {
+				t.Errorf("expected model %q, got %q", tt.model, jc.Model)
+			}
+			if jc.BaseURL != tt.baseURL {
+				t.Errorf("expected base URL %q, got %q", tt.baseURL, jc.BaseURL)
+			}
+			if jc.APIKey != tt.apiKey {
+				t.Errorf("expected API key %q, got %q", tt.apiKey, jc.APIKey)
+			}
+			if jc.N != 1 || jc.Temperature != 0 {
+				t.Errorf("expected deterministic single completion, got n=%d temperature=%v", jc.N, jc.Temperature)
+			}
+			// The original config must not be modified
+			if tt.config.N != 5 {
+				t.Errorf("judgeConfig modified the original config")
+			}
+		})
+	}
+}
+
+func TestJudgeAPIKeyRequired(t *testing.T) {
+	t.Setenv("INPUT_API_KEY", "primary-key")
+	t.Setenv("INPUT_INPUT_PROMPT", "Hello")
+	t.Setenv("INPUT_JUDGE_BASE_URL", "https://judge.example.com/v1")
+
+	if _, err := LoadConfig(); err != errJudgeAPIKeyRequired {
+		t.Errorf("LoadConfig() error = %v, want errJudgeAPIKeyRequired", err)
+	}
+
+	config := &Config{
+		BaseURL:      "https://api.openai.com/v1",
+		APIKey:       "primary-key",
+		JudgeBaseURL: "https://judge.example.com/v1",
+	}
+	if _, err := newJudgeClient(config, nil); err != errJudgeAPIKeyRequired {
+		t.Errorf("newJudgeClient() error = %v, want errJudgeAPIKeyRequired", err)
+	}
+
+	config.JudgeAPIKey = "judge-key"
+	if _, err := newJudgeClient(config, nil); err != nil {
+		t.Errorf("newJudgeClient() unexpected error: %v", err)
+	}
+}
+
+func TestEvaluateResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		arguments   string
+		expected    *JudgeResult
+		expectError bool
+	}{
+		{
+			name:      "Passing verdict",
+			arguments: `{"score": 8.5, "pass": true, "reasons": ["accurate", "concise"]}`,
+			expected:  &JudgeResult{Score: 8.5, Pass: true, Reasons: []string{"accurate", "concise"}},
+		},
+		{
+			name:      "Failing verdict",
+			arguments: `{"score": 2, "pass": false, "reasons": ["off topic"]}`,
+			expected:  &JudgeResult{Score: 2, Pass: false, Reasons: []string{"off topic"}},
+		},
+		{
+			name:        "Score out of range",
+			arguments:   `{"score": 42, "pass": true, "reasons": []}`,
+			expectError: true,
+		},
+		{
+			name:        "Invalid JSON",
+			arguments:   `not json`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured openai.ChatCompletionRequest
+			server := newChatCompletionServer(t, tt.arguments, &captured)
+			defer server.Close()
+
+			config := &Config{
+				BaseURL:     server.URL,
+				APIKey:      "test-key",
+				Model:       "gpt-4o-mini",
+				JudgeModel:  "gpt-4o",
+				JudgeRubric: "The answer must mention recursion.",
+			}
+			client, err := NewClient(config)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			messages := []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: "Explain recursion"},
+			}
+			got, usage, err := evaluateResponse(context.Background(), client, config, messages, "A function calling itself")
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Score != tt.expected.Score || got.Pass != tt.expected.Pass ||
+				strings.Join(got.Reasons, "|") != strings.Join(tt.expected.Reasons, "|") {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+			if usage.TotalTokens != 15 {
+				t.Errorf("expected usage of 15 tokens, got %d", usage.TotalTokens)
+			}
+
+			if captured.Model != "gpt-4o" {
+				t.Errorf("expected judge model gpt-4o, got %s", captured.Model)
+			}
+			if !strings.Contains(captured.Messages[0].Content, "The answer must mention recursion.") {
+				t.Errorf("system prompt is missing the rubric: %s", captured.Messages[0].Content)
+			}
+			if !strings.Contains(captured.Messages[1].Content, "A function calling itself") {
+				t.Errorf("judge input is missing the response: %s", captured.Messages[1].Content)
+			}
+		})
+	}
+}
+
+func TestJudgeResultMeetsMinScore(t *testing.T) {
+	result := &JudgeResult{Score: 7}
+
+	if !result.meetsMinScore(0) {
+		t.Error("expected score 7 to meet min_score 0")
+	}
+	if !result.meetsMinScore(7) {
+		t.Error("expected score 7 to meet min_score 7")
+	}
+	if result.meetsMinScore(7.5) {
+		t.Error("expected score 7 to fail min_score 7.5")
+	}
+}
+
+func TestAddJudgeResultToOutput(t *testing.T) {
+	output := map[string]string{}
+	err := addJudgeResultToOutput(output, &JudgeResult{Score: 6.5, Pass: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"judge_score":   "6.5",
+		"judge_pass":    "true",
+		"judge_reasons": "[]",
+	}
+	for key, value := range expected {
+		if output[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, output[key])
+		}
+	}
+}
+
+func TestEvaluateResponseProtectsRubric(t *testing.T) {
+	const apiKey = "sk-judge-test-1234567890"
+	tests := []struct {
+		name       string
+		redact     bool
+		wantErr    string
+		wantSystem string
+	}{
+		{name: "secret scan", wantErr: "refusing to send system message: it contains the configured api_key"},
+		{name: "redaction", redact: true, wantSystem: "Reply with [REDACTED_SECRET_1] to [REDACTED_EMAIL_2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured openai.ChatCompletionRequest
+			server := newChatCompletionServer(t, `{"score": 8, "pass": true, "reasons": []}`, &captured)
+			defer server.Close()
+
+			config := &Config{
+				BaseURL:     server.URL,
+				APIKey:      apiKey,
+				Model:       "gpt-4o",
+				JudgeRubric: "Reply with " + apiKey + " to carol@example.com",
+				SecretScan:  true,
+				Redact:      tt.redact,
+			}
+			client, err := NewClient(config)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			// The conversation was redacted before the completion
+			messages := []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: "Write to [REDACTED_EMAIL_1]"},
+			}
+			_, _, err = evaluateResponse(context.Background(), client, config, messages, "Done")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("evaluateResponse() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if captured.Model != "" {
+					t.Error("expected the judge request not to be sent")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("evaluateResponse() unexpected error: %v", err)
+			}
+			if system := captured.Messages[0].Content; strings.Contains(system, apiKey) ||
+				!strings.Contains(system, tt.wantSystem) {
+				t.Errorf("judge system prompt = %q, want it to contain %q", system, tt.wantSystem)
+			}
+			if !strings.Contains(captured.Messages[1].Content, "Write to [REDACTED_EMAIL_1]") {
+				t.Errorf("judge input = %q, want the redacted conversation", captured.Messages[1].Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/yassinebenaid/godump"
+)
+
+// Formats accepted by the log_format input
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logOutput receives the JSON log events, replaceable in tests
+var logOutput io.Writer = os.Stdout
+
+// jsonLogger emits structured log events when log_format is json, or is nil for the
+// default human readable output
+var jsonLogger *slog.Logger
+
+// setupLogging configures the output format from the log_format input
+func setupLogging() error {
+	format := strings.ToLower(strings.TrimSpace(os.Getenv("INPUT_LOG_FORMAT")))
+	switch format {
+	case "", logFormatText:
+		jsonLogger = nil
+	case logFormatJSON:
+		jsonLogger = slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	default:
+		return fmt.Errorf("invalid log_format value: %q (expected text or json)", format)
+	}
+	return nil
+}
+
+// logInfo prints msg in text mode, or emits an info event with the attributes in json mode
+func logInfo(event, msg string, attrs ...any) {
+	if jsonLogger != nil {
+		jsonLogger.Info(event, attrs...)
+		return
+	}
+	fmt.Println(msg)
+}
+
+// warnf prints a warning to stderr in text mode, or emits a warning event in json mode
+func warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if jsonLogger != nil {
+		jsonLogger.Warn("warning", "message", msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+}
+
+// logError reports the error that failed the step
+func logError(err error) {
+	if jsonLogger == nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	attrs := []any{"error", err.Error(), "error_class", errorClass(err)}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		attrs = append(attrs, "status_code", apiErr.HTTPStatusCode)
+	}
+	jsonLogger.Error("run_failed", attrs...)
+}
+
+// debugDump prints a value between banners in text mode, or emits it as a debug event
+// in json mode
+func debugDump(event, title string, value any) {
+	if jsonLogger != nil {
+		jsonLogger.Debug(event, "value", value)
+		return
+	}
+
+	banner := "=== Debug Mode: " + title + " ==="
+	fmt.Println(banner)
+	if err := godump.Dump(value); err != nil {
+		warnf("failed to dump %s: %v", strings.ToLower(title), err)
+	}
+	fmt.Println(strings.Repeat("=", len(banner)))
+}
+
+// logCompletion emits a completion event with the latency and usage of a chat
+// completion call in json mode. The kind tells regular completions apart from judge calls.
+func logCompletion(
+	config *Config,
+	kind string,
+	resp openai.ChatCompletionResponse,
+	latency time.Duration,
+	err error,
+) {
+	if jsonLogger == nil {
+		return
+	}
+
+	attrs := []any{"kind", kind, "model", config.Model, "latency_ms", latency.Milliseconds()}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error(), "error_class", errorClass(err))
+		jsonLogger.Error("completion", attrs...)
+		return
+	}
+
+	finishReasons := make([]string, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		finishReasons = append(finishReasons, string(choice.FinishReason))
+	}
+	attrs = append(attrs, "response_model", resp.Model, "finish_reasons", finishReasons, usageAttr(resp.Usage))
+	jsonLogger.Info("completion", attrs...)
+}
+
+// usageAttr groups token usage for log events
+func usageAttr(usage openai.Usage) slog.Attr {
+	attrs := []any{
+		"prompt_tokens", usage.PromptTokens,
+		"completion_tokens", usage.CompletionTokens,
+		"total_tokens", usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		attrs = append(attrs, "cached_tokens", usage.PromptTokensDetails.CachedTokens)
+	}
+	if d := usage.CompletionTokensDetails; d != nil {
+		attrs = append(attrs, "reasoning_tokens", d.ReasoningTokens)
+	}
+	return slog.Group("usage", attrs...)
+}
+
+// errorClass names the kind of failure for log events
+func errorClass(err error) string {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var hostErr *hostNotAllowedError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return "api_error"
+	case errors.As(err, &reqErr):
+		return "request_error"
+	case errors.As(err, &hostErr):
+		return "host_not_allowed"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network_error"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// captureJSONLogs enables json logging into a buffer for the duration of the test
+func captureJSONLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logOutput = &buf
+	t.Setenv("INPUT_LOG_FORMAT", "json")
+	if err := setupLogging(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		logOutput = os.Stdout
+		jsonLogger = nil
+	})
+	return &buf
+}
+
+// parseLogEvents decodes the JSON log lines by event name
+func parseLogEvents(t *testing.T, data []byte) map[string]map[string]any {
+	t.Helper()
+
+	events := make(map[string]map[string]any)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		events[event["msg"].(string)] = event
+	}
+	return events
+}
+
+func TestSetupLogging(t *testing.T) {
+	t.Cleanup(func() { jsonLogger = nil })
+
+	for _, format := range []string{"", "text", " TEXT "} {
+		t.Setenv("INPUT_LOG_FORMAT", format)
+		if err := setupLogging(); err != nil || jsonLogger != nil {
+			t.Errorf("setupLogging(%q) = %v, want text logging", format, err)
+		}
+	}
+
+	t.Setenv("INPUT_LOG_FORMAT", "Json")
+	if err := setupLogging(); err != nil || jsonLogger == nil {
+		t.Errorf("setupLogging(json) = %v, want json logging", err)
+	}
+
+	t.Setenv("INPUT_LOG_FORMAT", "yaml")
+	if err := setupLogging(); err == nil || !strings.Contains(err.Error(), "invalid log_format value") {
+		t.Errorf("setupLogging(yaml) error = %v, want invalid log_format value", err)
+	}
+}
+
+func TestRunWithJSONLogs(t *testing.T) {
+	server := newEchoServer(t, nil, nil)
+	defer server.Close()
+
+	outputFile := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	t.Setenv("INPUT_BASE_URL", server.URL)
+	t.Setenv("INPUT_API_KEY", "sk-test-1234567890")
+	t.Setenv("INPUT_MODEL", "gpt-4o")
+	t.Setenv("INPUT_INPUT_PROMPT", "Hello")
+	t.Setenv("INPUT_DEBUG", "true")
+	buf := captureJSONLogs(t)
+
+	if err := run(); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "sk-test-1234567890") {
+		t.Error("expected the API key to be masked in the logs")
+	}
+
+	events := parseLogEvents(t, buf.Bytes())
+	for _, name := range []string{"debug_config", "debug_messages", "request", "completion", "response", "token_usage"} {
+		if _, ok := events[name]; !ok {
+			t.Errorf("missing %q event in %s", name, buf)
+		}
+	}
+
+	config, _ := events["debug_config"]["value"].(map[string]any)
+	if config["APIKey"] != "sk-t********7890" {
+		t.Errorf("debug_config APIKey = %v, want the masked key", config["APIKey"])
+	}
+	if events["debug_config"]["level"] != "DEBUG" {
+		t.Errorf("debug_config level = %v, want DEBUG", events["debug_config"]["level"])
+	}
+
+	completion := events["completion"]
+	usage, _ := completion["usage"].(map[string]any)
+	if completion["kind"] != auditKindCompletion || completion["model"] != "gpt-4o" ||
+		usage["total_tokens"] != float64(5) {
+		t.Errorf("completion event = %v", completion)
+	}
+	if _, ok := completion["latency_ms"].(float64); !ok {
+		t.Errorf("completion latency_ms = %v, want a number", completion["latency_ms"])
+	}
+	if events["response"]["response"] != "gpt-4o: Hello" {
+		t.Errorf("response event = %v", events["response"])
+	}
+}
+
+func TestJudgeCallsAreLogged(t *testing.T) {
+	server := newChatCompletionServer(t, `{"score": 8, "pass": true, "reasons": [], "index": 1}`, nil)
+	defer server.Close()
+	buf := captureJSONLogs(t)
+
+	config := &Config{
+		BaseURL: server.URL, APIKey: "test-key", Model: "gpt-4o", JudgeModel: "gpt-4o-mini", JudgeRubric: "Be accurate",
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Explain recursion"}}
+	if _, _, err := evaluateResponse(context.Background(), client, config, messages, "A function"); err != nil {
+		t.Fatalf("evaluateResponse() unexpected error: %v", err)
+	}
+	if _, err := selectByJudge(context.Background(), client, config, messages, []string{"a", "b"}); err != nil {
+		t.Fatalf("selectByJudge() unexpected error: %v", err)
+	}
+
+	var kinds []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if event["msg"] != "completion" {
+			continue
+		}
+		usage, _ := event["usage"].(map[string]any)
+		if event["model"] != "gpt-4o-mini" || usage["total_tokens"] != float64(15) {
+			t.Errorf("judge completion event = %v", event)
+		}
+		kinds = append(kinds, fmt.Sprint(event["kind"]))
+	}
+	if got := strings.Join(kinds, ","); got != auditKindJudgeEvaluation+","+auditKindJudgeSelection {
+		t.Errorf("completion event kinds = %q, want one per judge call", got)
+	}
+}
+
+func TestLogError(t *testing.T) {
+	buf := captureJSONLogs(t)
+
+	logError(fmt.Errorf("chat completion error: %w", &openai.APIError{HTTPStatusCode: 429, Message: "slow down"}))
+
+	event := parseLogEvents(t, buf.Bytes())["run_failed"]
+	if event["level"] != "ERROR" || event["error_class"] != "api_error" || event["status_code"] != float64(429) {
+		t.Errorf("run_failed event = %v", event)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&openai.APIError{HTTPStatusCode: 500}, "api_error"},
+		{fmt.Errorf("wrapped: %w", &openai.RequestError{HTTPStatusCode: 502}), "request_error"},
+		{&hostNotAllowedError{"example.com"}, "host_not_allowed"},
+		{fmt.Errorf("request: %w", context.DeadlineExceeded), "timeout"},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "network_error"},
+		{errors.New("boom"), "error"},
+	}
+
+	for _, tt := range tests {
+		if got := errorClass(tt.err); got != tt.want {
+			t.Errorf("errorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
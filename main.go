@@ -2,22 +2,41 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/appleboy/com/gh"
 	openai "github.com/sashabaranov/go-openai"
-	"github.com/yassinebenaid/godump"
 )
 
 func main() {
-	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if err := execute(os.Args[1:]); err != nil {
+		logError(err)
 		os.Exit(1)
 	}
 }
 
+// execute dispatches to a subcommand, running the action when none is given
+func execute(args []string) error {
+	if err := setupLogging(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "eval":
+			return runEval(args[1:])
+		case "verify-audit":
+			return runVerifyAudit(args[1:])
+		}
+	}
+
+	return run()
+}
+
 // maskAPIKey masks the API key for secure logging
 func maskAPIKey(apiKey string) string {
 	const maskPattern = "********"
@@ -31,6 +50,11 @@ func maskAPIKey(apiKey string) string {
 
 // printTokenUsage prints token usage statistics to stdout
 func printTokenUsage(usage openai.Usage) {
+	if jsonLogger != nil {
+		jsonLogger.Info("token_usage", usageAttr(usage))
+		return
+	}
+
 	fmt.Println("--- Token Usage ---")
 	fmt.Printf("Prompt Tokens: %d\n", usage.PromptTokens)
 	fmt.Printf("Completion Tokens: %d\n", usage.CompletionTokens)
@@ -46,8 +70,57 @@ func printTokenUsage(usage openai.Usage) {
 	fmt.Println("--- End Token Usage ---")
 }
 
+// printResponse prints the final response between banners
+func printResponse(response string) {
+	if jsonLogger != nil {
+		jsonLogger.Info("response", "response", response)
+		return
+	}
+
+	fmt.Println("--- LLM Response ---")
+	fmt.Println(response)
+	fmt.Println("--- End Response ---")
+}
+
+// printSelection reports which candidate was selected and why
+func printSelection(config *Config, selection selectionResult, candidates int) {
+	if jsonLogger != nil {
+		jsonLogger.Info("selection",
+			"index", selection.Index, "candidates", candidates, "strategy", config.SelectionStrategy,
+			"votes", selection.Votes, "reason", selection.Reason)
+		return
+	}
+
+	fmt.Printf(
+		"Selected candidate %d of %d (strategy: %s, votes: %d)\n",
+		selection.Index, candidates, config.SelectionStrategy, selection.Votes,
+	)
+	if selection.Reason != "" {
+		fmt.Printf("Selection reason: %s\n", selection.Reason)
+	}
+}
+
+// printJudgeResult prints the score and reasons of the judge evaluation
+func printJudgeResult(result *JudgeResult) {
+	if jsonLogger != nil {
+		jsonLogger.Info("judge_evaluation",
+			"score", result.Score, "max_score", judgeMaxScore, "pass", result.Pass, "reasons", result.Reasons)
+		return
+	}
+
+	fmt.Println("--- Judge Evaluation ---")
+	fmt.Printf("Score: %v/%d\n", result.Score, judgeMaxScore)
+	fmt.Printf("Pass: %t\n", result.Pass)
+	for _, reason := range result.Reasons {
+		fmt.Printf("- %s\n", reason)
+	}
+	fmt.Println("--- End Judge Evaluation ---")
+}
+
 // addTokenUsageToOutput adds token usage metrics to the output map
 func addTokenUsageToOutput(output map[string]string, usage openai.Usage) {
+	activeMetrics.recordUsage(usage)
+
 	output["prompt_tokens"] = strconv.Itoa(usage.PromptTokens)
 	output["completion_tokens"] = strconv.Itoa(usage.CompletionTokens)
 	output["total_tokens"] = strconv.Itoa(usage.TotalTokens)
@@ -63,7 +136,68 @@ func addTokenUsageToOutput(output map[string]string, usage openai.Usage) {
 	}
 }
 
-// extractResponse extracts the response content from the API response
+// usageCost estimates the cost in USD of the usage from the configured token prices
+func usageCost(config *Config, usage openai.Usage) float64 {
+	return (float64(usage.PromptTokens)*config.InputTokenPrice +
+		float64(usage.CompletionTokens)*config.OutputTokenPrice) / 1_000_000
+}
+
+// addCostToOutput prints the estimated cost and adds it to the output map when
+// token prices are configured
+func addCostToOutput(output map[string]string, config *Config, usage openai.Usage) {
+	if config.InputTokenPrice == 0 && config.OutputTokenPrice == 0 {
+		return
+	}
+	cost := strconv.FormatFloat(usageCost(config, usage), 'f', 6, 64)
+	logInfo("cost", "Estimated Cost: $"+cost, "cost_usd", cost)
+	output["cost"] = cost
+}
+
+// sumUsage adds the token counts of two usage records
+func sumUsage(a, b openai.Usage) openai.Usage {
+	sum := openai.Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+
+	if a.PromptTokensDetails != nil || b.PromptTokensDetails != nil {
+		sum.PromptTokensDetails = &openai.PromptTokensDetails{}
+		for _, d := range []*openai.PromptTokensDetails{a.PromptTokensDetails, b.PromptTokensDetails} {
+			if d != nil {
+				sum.PromptTokensDetails.CachedTokens += d.CachedTokens
+				sum.PromptTokensDetails.AudioTokens += d.AudioTokens
+			}
+		}
+	}
+
+	if a.CompletionTokensDetails != nil || b.CompletionTokensDetails != nil {
+		sum.CompletionTokensDetails = &openai.CompletionTokensDetails{}
+		for _, d := range []*openai.CompletionTokensDetails{a.CompletionTokensDetails, b.CompletionTokensDetails} {
+			if d != nil {
+				sum.CompletionTokensDetails.AudioTokens += d.AudioTokens
+				sum.CompletionTokensDetails.ReasoningTokens += d.ReasoningTokens
+				sum.CompletionTokensDetails.AcceptedPredictionTokens += d.AcceptedPredictionTokens
+				sum.CompletionTokensDetails.RejectedPredictionTokens += d.RejectedPredictionTokens
+			}
+		}
+	}
+
+	return sum
+}
+
+// addCandidatesToOutput adds all candidates as a JSON array and the selected index to the output map
+func addCandidatesToOutput(output map[string]string, candidates []string, selected int) error {
+	data, err := json.Marshal(candidates)
+	if err != nil {
+		return fmt.Errorf("failed to marshal candidates: %w", err)
+	}
+	output["responses"] = string(data)
+	output["selected_index"] = strconv.Itoa(selected)
+	return nil
+}
+
+// extractResponse extracts the response content from the first choice of the API response
 func extractResponse(
 	resp openai.ChatCompletionResponse,
 	toolMeta *ToolMeta,
@@ -73,23 +207,28 @@ func extractResponse(
 		return "", fmt.Errorf("no response from LLM")
 	}
 
+	return extractChoice(resp.Choices[0], toolMeta, debug)
+}
+
+// extractChoice extracts the response content from a single choice
+func extractChoice(
+	choice openai.ChatCompletionChoice,
+	toolMeta *ToolMeta,
+	debug bool,
+) (string, error) {
 	if toolMeta != nil {
 		// Extract function call arguments when tool schema is used
-		if len(resp.Choices[0].Message.ToolCalls) > 0 {
+		if len(choice.Message.ToolCalls) > 0 {
 			// Debug: Print tool call details if debug mode is enabled
 			if debug {
-				fmt.Println("=== Debug Mode: Tool Calls ===")
-				if err := godump.Dump(resp.Choices[0].Message.ToolCalls); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to dump tool calls: %v\n", err)
-				}
-				fmt.Println("==============================")
+				debugDump("debug_tool_calls", "Tool Calls", choice.Message.ToolCalls)
 			}
-			return resp.Choices[0].Message.ToolCalls[0].Function.Arguments, nil
+			return choice.Message.ToolCalls[0].Function.Arguments, nil
 		}
 		return "", fmt.Errorf("expected tool call response but got none")
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	return choice.Message.Content, nil
 }
 
 // prepareToolSchema parses and validates the tool schema if provided
@@ -105,11 +244,7 @@ func prepareToolSchema(config *Config) (*ToolMeta, error) {
 
 	// Debug: Print tool schema if debug mode is enabled
 	if config.Debug {
-		fmt.Println("=== Debug Mode: Tool Schema ===")
-		if err := godump.Dump(toolMeta); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to dump tool schema: %v\n", err)
-		}
-		fmt.Println("===============================")
+		debugDump("debug_tool_schema", "Tool Schema", toolMeta)
 	}
 
 	return toolMeta, nil
@@ -128,6 +263,11 @@ func buildChatRequest(
 		MaxTokens:   config.MaxTokens,
 	}
 
+	// Request multiple candidates only when asked to, keeping the default payload unchanged
+	if config.N > 1 {
+		req.N = config.N
+	}
+
 	// Add tool if schema provided
 	if toolMeta != nil {
 		req.Tools = []openai.Tool{toolMeta.ToOpenAITool()}
@@ -143,23 +283,101 @@ func buildChatRequest(
 	return req
 }
 
-func run() error {
-	// Load configuration
+// completion is the outcome of a chat completion call after candidate selection
+type completion struct {
+	Candidates []string
+	Selection  selectionResult
+	// Usage includes tokens spent on candidate selection
+	Usage openai.Usage
+}
+
+// Response returns the selected candidate
+func (c *completion) Response() string {
+	return c.Candidates[c.Selection.Index]
+}
+
+// complete sends a chat completion request and selects the final response among the candidates
+func complete(
+	ctx context.Context,
+	client *openai.Client,
+	judgeClient *openai.Client,
+	config *Config,
+	messages []openai.ChatCompletionMessage,
+	toolMeta *ToolMeta,
+) (*completion, error) {
+	if err := scanMessagesForSecrets(config, messages); err != nil {
+		return nil, err
+	}
+
+	req := buildChatRequest(config, messages, toolMeta)
+	start := time.Now()
+	resp, err := tracedChatCompletion(ctx, client, config, req, auditKindCompletion)
+	logCompletion(config, auditKindCompletion, resp, time.Since(start), err)
+	if auditErr := auditCompletion(config, auditKindCompletion, req, resp, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chat completion error: %w", err)
+	}
+
+	candidates, err := extractCandidates(resp, toolMeta, config.Debug)
+	if err != nil {
+		return nil, err
+	}
+
+	selection, err := selectCandidate(ctx, judgeClient, config, messages, candidates, toolMeta != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &completion{
+		Candidates: candidates,
+		Selection:  selection,
+		Usage:      sumUsage(resp.Usage, selection.Usage),
+	}, nil
+}
+
+func run() (err error) {
+	// Trace the run when an OpenTelemetry exporter is configured
+	ctx, finishTrace, err := startTracing(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() { finishTrace(err) }()
+
+	// Record the usage of the run when a metrics destination is configured
+	finishMetrics, err := startMetrics()
+	if err != nil {
+		return err
+	}
+	defer func() { finishMetrics(err) }()
+
+	// Keep a tamper-evident record of the requests when an audit file is configured
+	finishAudit, err := startAudit()
+	if err != nil {
+		return err
+	}
+	defer finishAudit()
+
+	// Load configuration. The span is started without the run context so the prompt
+	// loading spans, which have no context, nest under it.
+	_, configSpan := startSpan(context.Background(), "load_config", spanKindInternal)
 	config, err := LoadConfig()
+	configSpan.finish(err)
 	if err != nil {
 		return err
 	}
+	activeMetrics.setConfig(config)
 
 	// Debug: Print all parameters if debug mode is enabled
 	if config.Debug {
-		fmt.Println("=== Debug Mode: All Parameters ===")
 		// Create a copy of config with masked API key for secure logging
 		debugConfig := *config
 		debugConfig.APIKey = maskAPIKey(config.APIKey)
-		if err := godump.Dump(debugConfig); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to dump config: %v\n", err)
+		if config.JudgeAPIKey != "" {
+			debugConfig.JudgeAPIKey = maskAPIKey(config.JudgeAPIKey)
 		}
-		fmt.Println("===================================")
+		debugDump("debug_config", "All Parameters", debugConfig)
 	}
 
 	// Create OpenAI client
@@ -168,8 +386,19 @@ func run() error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	// Build messages
-	messages := BuildMessages(config)
+	// Create judge client (reuses the primary client unless overridden)
+	judgeClient, err := newJudgeClient(config, client)
+	if err != nil {
+		return err
+	}
+
+	// Build messages, replacing secrets and personal data when redaction is enabled
+	redactor := newRedactor(config)
+	messages := redactor.redactMessages(BuildMessages(config))
+	if redactor != nil && config.BatchInputs == "" && config.ChunkMode == "" {
+		logInfo("redaction", fmt.Sprintf("Redacted %d value(s) from the messages", redactor.Count()),
+			"redactions", redactor.Count())
+	}
 
 	// Parse and validate tool schema if provided
 	toolMeta, err := prepareToolSchema(config)
@@ -179,39 +408,71 @@ func run() error {
 
 	// Debug: Print messages if debug mode is enabled
 	if config.Debug {
-		fmt.Println("=== Debug Mode: Messages ===")
-		if err := godump.Dump(messages); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to dump messages: %v\n", err)
-		}
-		fmt.Println("============================")
+		debugDump("debug_messages", "Messages", messages)
 	}
 
-	// Create chat completion request with optional tool support
-	req := buildChatRequest(config, messages, toolMeta)
+	// Print the request instead of sending it
+	if config.DryRun {
+		return runDryRun(config, messages, toolMeta)
+	}
 
-	fmt.Println("Sending request to LLM...")
-	fmt.Printf("Model: %s\n", config.Model)
-	fmt.Printf("Base URL: %s\n", config.BaseURL)
+	// Run the input prompt over every batch item instead of a single request
+	if config.BatchInputs != "" {
+		return runBatch(ctx, config, client, judgeClient, toolMeta)
+	}
 
-	// Call the API
-	resp, err := client.CreateChatCompletion(context.Background(), req)
-	if err != nil {
-		return fmt.Errorf("chat completion error: %w", err)
+	// Review every changed file of the pull request in a completion of its own
+	if config.ChunkMode != "" {
+		return runChunks(ctx, config, client, judgeClient, toolMeta)
+	}
+
+	if jsonLogger != nil {
+		jsonLogger.Info("request", "model", config.Model, "base_url", config.BaseURL)
+	} else {
+		fmt.Println("Sending request to LLM...")
+		fmt.Printf("Model: %s\n", config.Model)
+		fmt.Printf("Base URL: %s\n", config.BaseURL)
 	}
 
-	// Extract response content
-	response, err := extractResponse(resp, toolMeta, config.Debug)
+	// Call the API and pick the final response among the candidates
+	result, err := complete(ctx, client, judgeClient, config, messages, toolMeta)
 	if err != nil {
 		return err
 	}
+	candidates, selection := result.Candidates, result.Selection
+	response := result.Response()
+	usage := result.Usage
+
+	if len(candidates) > 1 {
+		printSelection(config, selection, len(candidates))
+	}
 
 	// Print response for debugging
-	fmt.Println("--- LLM Response ---")
-	fmt.Println(response)
-	fmt.Println("--- End Response ---")
+	printResponse(response)
+
+	// Score the response with the judge model if a rubric is configured
+	var judgeResult *JudgeResult
+	if config.judgeEnabled() {
+		var judgeUsage openai.Usage
+		judgeResult, judgeUsage, err = evaluateResponse(ctx, judgeClient, config, messages, response)
+		if err != nil {
+			return err
+		}
+		usage = sumUsage(usage, judgeUsage)
+
+		printJudgeResult(judgeResult)
+	}
+
+	// Put redacted values back into the response if requested
+	if config.RedactRestore {
+		response = redactor.restore(response)
+		for i := range candidates {
+			candidates[i] = redactor.restore(candidates[i])
+		}
+	}
 
 	// Print token usage statistics
-	printTokenUsage(resp.Usage)
+	printTokenUsage(usage)
 
 	// Set GitHub Actions output
 	var toolArgs map[string]string
@@ -227,19 +488,37 @@ func run() error {
 	// Build output map with raw response and tool arguments
 	output, reservedFieldSkipped := BuildOutputMap(response, toolArgs)
 	if reservedFieldSkipped {
-		fmt.Fprintf(
-			os.Stderr,
-			"Warning: tool schema field '%s' is reserved and will be skipped\n",
-			ReservedOutputField,
-		)
+		warnf("tool schema field '%s' is reserved and will be skipped", ReservedOutputField)
 	}
 
 	// Add token usage metrics to output
-	addTokenUsageToOutput(output, resp.Usage)
+	addTokenUsageToOutput(output, usage)
+	addCostToOutput(output, config, usage)
+	output["redactions"] = strconv.Itoa(redactor.Count())
+
+	// Expose every candidate when multiple completions were requested
+	if config.N > 1 {
+		if err := addCandidatesToOutput(output, candidates, selection.Index); err != nil {
+			return err
+		}
+	}
+
+	if judgeResult != nil {
+		if err := addJudgeResultToOutput(output, judgeResult); err != nil {
+			return err
+		}
+	}
 
 	if err := gh.SetOutput(output); err != nil {
 		return fmt.Errorf("failed to set output: %w", err)
 	}
 
+	// Fail the step after outputs are written so the verdict stays inspectable
+	if judgeResult != nil && !judgeResult.meetsMinScore(config.MinScore) {
+		return fmt.Errorf(
+			"judge score %v is below min_score %v", judgeResult.Score, config.MinScore,
+		)
+	}
+
 	return nil
 }
@@ -14,10 +14,14 @@ func BuildMessages(config *Config) []openai.ChatCompletionMessage {
 		})
 	}
 
-	// Add user prompt
+	// Add user prompt, followed by any context such as the pull request diff
+	content := config.InputPrompt
+	if config.Context != "" {
+		content += "\n\n" + config.Context
+	}
 	messages = append(messages, openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleUser,
-		Content: config.InputPrompt,
+		Content: content,
 	})
 
 	return messages
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	metricsExportTimeout = 10 * time.Second
+	// metricsJob is the job label of the metrics pushed to a Prometheus pushgateway
+	metricsJob = "llm_action"
+)
+
+// Run statuses of a metrics record
+const (
+	metricsStatusSuccess = "success"
+	metricsStatusFailure = "failure"
+)
+
+// activeMetrics collects the usage of the current run, or is nil when no metrics
+// destination is configured
+var activeMetrics *metricsRecorder
+
+// metricsRecorder builds the metrics record of a run and exports it to a file, a JSON
+// endpoint or a Prometheus pushgateway when the run finishes
+type metricsRecorder struct {
+	mu          sync.Mutex
+	file        string
+	endpoint    string
+	pushgateway string
+	headers     map[string]string
+	start       time.Time
+	config      *Config
+	usage       openai.Usage
+}
+
+// metricsRecord is the JSON line written for every run
+type metricsRecord struct {
+	Timestamp        string   `json:"timestamp"`
+	Repository       string   `json:"repository,omitempty"`
+	Workflow         string   `json:"workflow,omitempty"`
+	RunID            string   `json:"run_id,omitempty"`
+	Model            string   `json:"model,omitempty"`
+	ProviderHost     string   `json:"provider_host,omitempty"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	TotalTokens      int      `json:"total_tokens"`
+	CachedTokens     int      `json:"cached_tokens"`
+	CacheHit         bool     `json:"cache_hit"`
+	Cost             *float64 `json:"cost,omitempty"`
+	LatencyMs        int64    `json:"latency_ms"`
+	Status           string   `json:"status"`
+	ErrorClass       string   `json:"error_class,omitempty"`
+}
+
+// newMetricsRecorder creates a recorder from the metrics_* inputs. It returns nil when
+// no destination is configured.
+func newMetricsRecorder() (*metricsRecorder, error) {
+	m := &metricsRecorder{
+		file:  strings.TrimSpace(os.Getenv("INPUT_METRICS_FILE")),
+		start: time.Now(),
+	}
+
+	for _, dest := range []struct {
+		input string
+		value *string
+	}{
+		{"metrics_endpoint", &m.endpoint},
+		{"metrics_pushgateway", &m.pushgateway},
+	} {
+		raw := strings.TrimSpace(os.Getenv("INPUT_" + strings.ToUpper(dest.input)))
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid %s value: %q (expected an http or https URL)", dest.input, raw)
+		}
+		*dest.value = raw
+	}
+
+	if m.file == "" && m.endpoint == "" && m.pushgateway == "" {
+		return nil, nil
+	}
+
+	headers, err := parseHeaderList(os.Getenv("INPUT_METRICS_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics_headers value: %w", err)
+	}
+	m.headers = headers
+
+	return m, nil
+}
+
+// startMetrics enables metrics collection when a destination is configured. The
+// returned function exports the record of the run with its final error.
+func startMetrics() (func(error), error) {
+	m, err := newMetricsRecorder()
+	if err != nil || m == nil {
+		return func(error) {}, err
+	}
+
+	activeMetrics = m
+	return func(err error) {
+		activeMetrics = nil
+		if err := m.export(m.record(err)); err != nil {
+			warnf("%v", err)
+		}
+	}, nil
+}
+
+// setConfig records the model and provider of the run
+func (m *metricsRecorder) setConfig(config *Config) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = config
+}
+
+// recordUsage records the total token usage of the run
+func (m *metricsRecorder) recordUsage(usage openai.Usage) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = usage
+}
+
+// record builds the metrics record of the run
+func (m *metricsRecorder) record(err error) metricsRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := metricsRecord{
+		Timestamp:        m.start.UTC().Format(time.RFC3339),
+		Repository:       os.Getenv("GITHUB_REPOSITORY"),
+		Workflow:         os.Getenv("GITHUB_WORKFLOW"),
+		RunID:            os.Getenv("GITHUB_RUN_ID"),
+		PromptTokens:     m.usage.PromptTokens,
+		CompletionTokens: m.usage.CompletionTokens,
+		TotalTokens:      m.usage.TotalTokens,
+		LatencyMs:        time.Since(m.start).Milliseconds(),
+		Status:           metricsStatusSuccess,
+	}
+	if d := m.usage.PromptTokensDetails; d != nil {
+		r.CachedTokens = d.CachedTokens
+		r.CacheHit = d.CachedTokens > 0
+	}
+	if err != nil {
+		r.Status = metricsStatusFailure
+		r.ErrorClass = errorClass(err)
+	}
+
+	if config := m.config; config != nil {
+		r.Model = config.Model
+		if u, err := url.Parse(config.BaseURL); err == nil {
+			r.ProviderHost = u.Hostname()
+		}
+		if config.InputTokenPrice != 0 || config.OutputTokenPrice != 0 {
+			cost := usageCost(config, m.usage)
+			r.Cost = &cost
+		}
+	}
+	return r
+}
+
+// export appends the record to the metrics file and sends it to the configured endpoints
+func (m *metricsRecorder) export(r metricsRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	if m.file != "" {
+		f, err := os.OpenFile(m.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open metrics_file: %w", err)
+		}
+		_, err = f.Write(append(data, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write metrics_file: %w", err)
+		}
+	}
+
+	if m.endpoint != "" {
+		if err := m.post(m.endpoint, "application/json", data); err != nil {
+			return fmt.Errorf("failed to export metrics: %w", err)
+		}
+	}
+
+	if m.pushgateway != "" {
+		target := strings.TrimSuffix(m.pushgateway, "/") + "/metrics/job/" + metricsJob
+		if r.Repository != "" {
+			// Repository names contain a slash, which the pushgateway accepts base64 encoded
+			target += "/repository@base64/" + base64.RawURLEncoding.EncodeToString([]byte(r.Repository))
+		}
+		if err := m.post(target, "text/plain; version=0.0.4", []byte(prometheusText(r))); err != nil {
+			return fmt.Errorf("failed to push metrics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// post sends the body to the target with the metrics headers
+func (m *metricsRecorder) post(target, contentType string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsExportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", GetUserAgent())
+	for key, value := range m.headers {
+		req.Header.Set(key, value)
+	}
+
+	// The default client keeps the export out of the trace
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s returned %s", redactURL(req.URL), resp.Status)
+	}
+	return nil
+}
+
+// prometheusText renders the record as gauges in the Prometheus text exposition format
+func prometheusText(r metricsRecord) string {
+	labels := fmt.Sprintf(`model="%s",provider="%s",status="%s"`,
+		escapeLabel(r.Model), escapeLabel(r.ProviderHost), r.Status)
+
+	var b strings.Builder
+	gauge := func(name, help string, value any) {
+		fmt.Fprintf(&b, "# HELP llm_action_%s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE llm_action_%s gauge\n", name)
+		fmt.Fprintf(&b, "llm_action_%s{%s} %v\n", name, labels, value)
+	}
+
+	gauge("prompt_tokens", "Prompt tokens used by the last run.", r.PromptTokens)
+	gauge("completion_tokens", "Completion tokens used by the last run.", r.CompletionTokens)
+	gauge("total_tokens", "Total tokens used by the last run.", r.TotalTokens)
+	gauge("cached_tokens", "Cached prompt tokens of the last run.", r.CachedTokens)
+	if r.Cost != nil {
+		gauge("cost_usd", "Estimated cost in USD of the last run.", *r.Cost)
+	}
+	gauge("latency_seconds", "Duration of the last run in seconds.", float64(r.LatencyMs)/1000)
+	gauge("last_run_timestamp_seconds", "Unix time of the last run.", timestampSeconds(r.Timestamp))
+	return b.String()
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// timestampSeconds converts an RFC 3339 timestamp to Unix seconds
+func timestampSeconds(timestamp string) int64 {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewMetricsRecorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantNil bool
+		wantErr string
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "file", env: map[string]string{"INPUT_METRICS_FILE": "metrics.jsonl"}},
+		{name: "endpoint", env: map[string]string{"INPUT_METRICS_ENDPOINT": "https://metrics.example.com/llm"}},
+		{name: "pushgateway", env: map[string]string{"INPUT_METRICS_PUSHGATEWAY": "http://pushgateway:9091"}},
+		{
+			name:    "invalid endpoint",
+			env:     map[string]string{"INPUT_METRICS_ENDPOINT": "metrics.example.com"},
+			wantErr: "invalid metrics_endpoint value",
+		},
+		{
+			name:    "invalid pushgateway",
+			env:     map[string]string{"INPUT_METRICS_PUSHGATEWAY": "ftp://pushgateway"},
+			wantErr: "invalid metrics_pushgateway value",
+		},
+		{
+			name:    "invalid headers",
+			env:     map[string]string{"INPUT_METRICS_FILE": "metrics.jsonl", "INPUT_METRICS_HEADERS": "no-colon"},
+			wantErr: "invalid metrics_headers value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			m, err := newMetricsRecorder()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newMetricsRecorder() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newMetricsRecorder() unexpected error: %v", err)
+			}
+			if (m == nil) != tt.wantNil {
+				t.Errorf("newMetricsRecorder() = %+v, want nil %v", m, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestRunWithMetrics(t *testing.T) {
+	llm := newEchoServer(t, nil, nil)
+	defer llm.Close()
+
+	var posted []byte
+	var auth string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		posted, _ = io.ReadAll(r.Body)
+	}))
+	defer endpoint.Close()
+
+	var pushPath, pushed string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		pushed = string(body)
+	}))
+	defer pushgateway.Close()
+
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "output")
+	if err := os.WriteFile(outputFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	metricsFile := filepath.Join(dir, "metrics.jsonl")
+
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_WORKFLOW", "Review")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("INPUT_BASE_URL", llm.URL)
+	t.Setenv("INPUT_API_KEY", "test-key")
+	t.Setenv("INPUT_MODEL", "gpt-4o")
+	t.Setenv("INPUT_INPUT_PROMPT", "Hello")
+	t.Setenv("INPUT_INPUT_TOKEN_PRICE", "2")
+	t.Setenv("INPUT_OUTPUT_TOKEN_PRICE", "10")
+	t.Setenv("INPUT_METRICS_FILE", metricsFile)
+	t.Setenv("INPUT_METRICS_ENDPOINT", endpoint.URL)
+	t.Setenv("INPUT_METRICS_HEADERS", "Authorization: Bearer metrics-token")
+	t.Setenv("INPUT_METRICS_PUSHGATEWAY", pushgateway.URL)
+
+	if err := run(); err != nil {
+		t.Fatalf("run() unexpected error: %v", err)
+	}
+	if activeMetrics != nil {
+		t.Error("expected metrics to be disabled after the run")
+	}
+
+	content, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatalf("failed to read metrics_file: %v", err)
+	}
+	if strings.TrimSpace(string(content)) != string(posted) {
+		t.Errorf("metrics_file = %s, want the posted record %s", content, posted)
+	}
+	if auth != "Bearer metrics-token" {
+		t.Errorf("metrics endpoint Authorization = %q, want the metrics_headers value", auth)
+	}
+
+	var record metricsRecord
+	if err := json.Unmarshal(posted, &record); err != nil {
+		t.Fatalf("invalid metrics record %s: %v", posted, err)
+	}
+	if record.Repository != "owner/repo" || record.Workflow != "Review" || record.RunID != "42" ||
+		record.Model != "gpt-4o" || record.ProviderHost != "127.0.0.1" || record.TotalTokens != 5 ||
+		record.Status != metricsStatusSuccess || record.CacheHit || record.Timestamp == "" {
+		t.Errorf("metrics record = %s", posted)
+	}
+	if record.Cost == nil || *record.Cost != 0.000026 {
+		t.Errorf("metrics cost = %v, want 0.000026", record.Cost)
+	}
+
+	if pushPath != "/metrics/job/llm_action/repository@base64/b3duZXIvcmVwbw" {
+		t.Errorf("pushgateway path = %q", pushPath)
+	}
+	want := `llm_action_total_tokens{model="gpt-4o",provider="127.0.0.1",status="success"} 5`
+	if !strings.Contains(pushed, want+"\n") || !strings.Contains(pushed, "# TYPE llm_action_cost_usd gauge\n") {
+		t.Errorf("pushed metrics = %q, want them to contain %q", pushed, want)
+	}
+}
+
+func TestRunWithMetricsFailure(t *testing.T) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"message": "invalid api key", "type": "invalid_request_error"}}`))
+	}))
+	defer llm.Close()
+
+	metricsFile := filepath.Join(t.TempDir(), "metrics.jsonl")
+	t.Setenv("INPUT_BASE_URL", llm.URL)
+	t.Setenv("INPUT_API_KEY", "test-key")
+	t.Setenv("INPUT_INPUT_PROMPT", "Hello")
+	t.Setenv("INPUT_METRICS_FILE", metricsFile)
+
+	if err := run(); err == nil {
+		t.Fatal("run() expected an error")
+	}
+
+	content, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatalf("failed to read metrics_file: %v", err)
+	}
+	var record metricsRecord
+	if err := json.Unmarshal(content, &record); err != nil {
+		t.Fatalf("invalid metrics record %s: %v", content, err)
+	}
+	if record.Status != metricsStatusFailure || record.ErrorClass != "api_error" || record.Cost != nil {
+		t.Errorf("metrics record = %s, want a failed api_error run without cost", content)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("escapeLabel() = %q", got)
+	}
+}
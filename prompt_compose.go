@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Formats of the sources in a composed prompt
+const (
+	composeFormatPlain   = "plain"
+	composeFormatHeading = "heading"
+	composeFormatFence   = "fence"
+)
+
+// composeFormats lists the valid prompt_source_format values
+var composeFormats = []string{composeFormatPlain, composeFormatHeading, composeFormatFence}
+
+// defaultPromptMaxBytes limits the size of a composed prompt
+const defaultPromptMaxBytes = 1024 * 1024
+
+// fenceLanguages maps file extensions to code fence languages
+var fenceLanguages = map[string]string{
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".css": "css", ".diff": "diff", ".patch": "diff", ".go": "go", ".html": "html",
+	".java": "java", ".js": "javascript", ".jsx": "jsx", ".json": "json", ".jsonl": "json",
+	".kt": "kotlin", ".md": "markdown", ".php": "php", ".py": "python", ".rb": "ruby",
+	".rs": "rust", ".sh": "bash", ".sql": "sql", ".swift": "swift", ".toml": "toml",
+	".ts": "typescript", ".tsx": "tsx", ".xml": "xml", ".yaml": "yaml", ".yml": "yaml",
+}
+
+// promptComposer builds a prompt from a YAML list of sources
+type promptComposer struct {
+	separator string
+	format    string
+	maxBytes  int64
+	render    bool
+	strict    bool
+}
+
+// promptPart is a loaded source of a composed prompt. Name is empty for literal text.
+type promptPart struct {
+	Name    string
+	Content string
+}
+
+// compose loads every source of the list, renders it when enabled, and joins the
+// formatted parts with the separator
+func (c *promptComposer) compose(input string) (string, error) {
+	var sources []string
+	if err := yaml.Unmarshal([]byte(input), &sources); err != nil || len(sources) == 0 {
+		return "", fmt.Errorf("invalid source list: expected a YAML list of sources")
+	}
+
+	var b strings.Builder
+	for _, source := range sources {
+		parts, err := c.load(source)
+		if err != nil {
+			return "", err
+		}
+
+		for _, part := range parts {
+			if b.Len() > 0 {
+				b.WriteString(c.separator)
+			}
+			b.WriteString(c.formatPart(part))
+			if int64(b.Len()) > c.maxBytes {
+				return "", fmt.Errorf("composed prompt exceeds prompt_max_bytes (%d bytes)", c.maxBytes)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// load loads a single list entry, expanding glob patterns to one part per file
+func (c *promptComposer) load(source string) ([]promptPart, error) {
+	location, pinned := splitIntegrity(source)
+	names := []string{location}
+	if isGlobSource(location) {
+		if pinned != "" {
+			return nil, fmt.Errorf("cannot pin pattern %s to a sha256 digest", location)
+		}
+		files, err := globFiles(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", location, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no files match %s", location)
+		}
+		names = files
+		source = ""
+	}
+
+	parts := make([]promptPart, 0, len(names))
+	for _, name := range names {
+		input := source
+		if input == "" {
+			input = name
+		}
+
+		content, err := LoadContent(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", describeSource(name), err)
+		}
+		if c.render {
+			if content, err = renderPrompt(content, c.strict); err != nil {
+				return nil, fmt.Errorf("failed to load %s: %w", describeSource(name), err)
+			}
+		}
+		parts = append(parts, promptPart{Name: sourceName(name), Content: content})
+	}
+	return parts, nil
+}
+
+// isGlobSource reports whether a list entry is a file pattern such as "reports/*.txt"
+// rather than literal text, which has whitespace, or another kind of source
+func isGlobSource(source string) bool {
+	return strings.ContainsAny(source, "*?[") && !strings.ContainsAny(source, " \t\n") &&
+		detectSource(source) == sourceText
+}
+
+// sourceName returns the name shown in headers, or an empty string for inline content
+func sourceName(source string) string {
+	switch detectSource(source) {
+	case sourceFile:
+		return strings.TrimPrefix(source, "file://")
+	case sourceURL, sourceGitHub, sourceGit:
+		return source
+	case sourceEnv:
+		return strings.TrimPrefix(source, envScheme)
+	default:
+		return ""
+	}
+}
+
+// formatPart adds the configured header to a named part. Literal text gets no header.
+// Trailing newlines are dropped so the separator alone sets the spacing between parts.
+func (c *promptComposer) formatPart(part promptPart) string {
+	content := strings.TrimRight(part.Content, "\n")
+	if part.Name == "" {
+		return content
+	}
+
+	switch c.format {
+	case composeFormatHeading:
+		return "## " + part.Name + "\n\n" + content
+	case composeFormatFence:
+		fence := codeFence(content)
+		info := strings.TrimSpace(fenceLanguage(part.Name) + " " + part.Name)
+		return fence + info + "\n" + content + "\n" + fence
+	default:
+		return content
+	}
+}
+
+// fenceLanguage returns the code fence language of a file name or URL
+func fenceLanguage(name string) string {
+	name = strings.SplitN(name, "?", 2)[0]
+	if isGitHubSource(name) || isGitSource(name) {
+		name, _ = splitRef(name)
+	}
+	return fenceLanguages[strings.ToLower(path.Ext(name))]
+}
+
+// codeFence returns a backtick fence longer than any backtick run in the content
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComposePrompt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("--- a/main.go\n+++ b/main.go\n"))
+	}))
+	defer server.Close()
+
+	t.Chdir(setupWorkspace(t, map[string]string{
+		"instructions.md":      "Review {{.GITHUB_REPOSITORY}}",
+		"reports/unit.txt":     "ok  pkg/a",
+		"reports/lint.txt":     "main.go:3: unused",
+		"src/main.go":          "package main\n",
+		"docs/fences.md":       "```go\nx := 1\n```\n",
+		"reports/skipped.json": "{}",
+	}))
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+
+	tests := []struct {
+		name      string
+		format    string
+		separator string
+		maxBytes  int64
+		render    bool
+		input     string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:   "plain parts with literal text",
+			render: true,
+			input:  "- instructions.md\n- Keep it short.\n- reports/*.txt",
+			want:   "Review owner/repo\n\nKeep it short.\n\nmain.go:3: unused\n\nok  pkg/a",
+		},
+		{
+			name:  "without rendering",
+			input: "- instructions.md",
+			want:  "Review {{.GITHUB_REPOSITORY}}",
+		},
+		{
+			name:      "custom separator",
+			separator: "\n---\n",
+			input:     "- One\n- Two",
+			want:      "One\n---\nTwo",
+		},
+		{
+			name:   "heading format",
+			format: composeFormatHeading,
+			input:  "- reports/unit.txt\n- Explain the failures",
+			want:   "## reports/unit.txt\n\nok  pkg/a\n\nExplain the failures",
+		},
+		{
+			name:   "fence format with language",
+			format: composeFormatFence,
+			input:  "- src/main.go\n- " + server.URL + "/pr.diff?page=1",
+			want: "```go src/main.go\npackage main\n```\n\n" +
+				"```diff " + server.URL + "/pr.diff?page=1\n--- a/main.go\n+++ b/main.go\n```",
+		},
+		{
+			name:   "fence longer than content fences",
+			format: composeFormatFence,
+			input:  "- docs/fences.md",
+			want:   "````markdown docs/fences.md\n```go\nx := 1\n```\n````",
+		},
+		{
+			name:   "multi-line literal text",
+			format: composeFormatFence,
+			input:  "- |\n  Line one\n  Line two\n- src/main.go",
+			want:   "Line one\nLine two\n\n```go src/main.go\npackage main\n```",
+		},
+		{name: "size limit", maxBytes: 20, input: "- reports/*.txt", wantErr: "exceeds prompt_max_bytes (20 bytes)"},
+		{name: "no matching files", input: "- reports/*.log", wantErr: "no files match reports/*.log"},
+		{name: "missing file", input: "- file://missing.md", wantErr: "failed to load file://missing.md"},
+		{name: "not a list", input: "just text", wantErr: "expected a YAML list of sources"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			composer := &promptComposer{
+				separator: "\n\n",
+				format:    composeFormatPlain,
+				maxBytes:  defaultPromptMaxBytes,
+				render:    tt.render,
+			}
+			if tt.format != "" {
+				composer.format = tt.format
+			}
+			if tt.separator != "" {
+				composer.separator = tt.separator
+			}
+			if tt.maxBytes != 0 {
+				composer.maxBytes = tt.maxBytes
+			}
+
+			got, err := composer.compose(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("compose() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compose() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("compose() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWithComposedPrompt(t *testing.T) {
+	t.Chdir(setupWorkspace(t, map[string]string{
+		"instructions.md": "Review {{.GITHUB_REPOSITORY}}",
+		"test-output.txt": "FAIL TestParse",
+	}))
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "list source",
+			env: map[string]string{
+				"INPUT_PROMPT_SOURCE":        "input_prompt=list",
+				"INPUT_PROMPT_SOURCE_FORMAT": "heading",
+				"INPUT_PROMPT_SEPARATOR":     `\n\n---\n\n`,
+			},
+			want: "## instructions.md\n\nReview owner/repo\n\n---\n\n## test-output.txt\n\nFAIL TestParse",
+		},
+		{
+			name: "template disabled",
+			env: map[string]string{
+				"INPUT_PROMPT_SOURCE":    "input_prompt=list",
+				"INPUT_TEMPLATE_DISABLE": "input_prompt",
+			},
+			want: "Review {{.GITHUB_REPOSITORY}}\n\nFAIL TestParse",
+		},
+		{
+			name:    "invalid format",
+			env:     map[string]string{"INPUT_PROMPT_SOURCE": "input_prompt=list", "INPUT_PROMPT_SOURCE_FORMAT": "table"},
+			wantErr: "invalid prompt_source_format value",
+		},
+		{
+			name:    "invalid max bytes",
+			env:     map[string]string{"INPUT_PROMPT_SOURCE": "input_prompt=list", "INPUT_PROMPT_MAX_BYTES": "0"},
+			wantErr: "prompt_max_bytes must be positive",
+		},
+		{
+			name: "companion digest refused",
+			env: map[string]string{
+				"INPUT_PROMPT_SOURCE":       "input_prompt=list",
+				"INPUT_INPUT_PROMPT_SHA256": strings.Repeat("0", 64),
+			},
+			wantErr: "input_prompt_sha256 is not supported for a list of sources",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_API_KEY", "test-key")
+			t.Setenv("INPUT_INPUT_PROMPT", "- instructions.md\n- test-output.txt")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			if config.InputPrompt != tt.want {
+				t.Errorf("InputPrompt = %q, want %q", config.InputPrompt, tt.want)
+			}
+		})
+	}
+}
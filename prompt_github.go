@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	githubScheme = "github://"
+	gitRefScheme = "gitref://"
+
+	// defaultGitHubAPIURL is used when GITHUB_API_URL is not set (GitHub Enterprise sets it)
+	defaultGitHubAPIURL = "https://api.github.com"
+
+	// gitTimeout bounds git commands run in the local checkout
+	gitTimeout = 30 * time.Second
+)
+
+// isGitHubSource checks if the input references a file in a GitHub repository
+func isGitHubSource(input string) bool {
+	return strings.HasPrefix(input, githubScheme)
+}
+
+// isGitSource checks if the input references a file in the local checkout at a git ref
+func isGitSource(input string) bool {
+	return strings.HasPrefix(input, gitRefScheme)
+}
+
+// splitRef splits "path@ref" at the last "@", returning an empty ref when there is none
+func splitRef(s string) (string, string) {
+	idx := strings.LastIndex(s, "@")
+	if idx == -1 {
+		return s, ""
+	}
+	return s[:idx], s[idx+1:]
+}
+
+// githubSource is a file in a GitHub repository at an optional ref
+type githubSource struct {
+	Owner string
+	Repo  string
+	Path  string
+	Ref   string
+}
+
+// parseGitHubSource parses github://owner/repo/path/to/file@ref
+func parseGitHubSource(input string) (*githubSource, error) {
+	location, ref := splitRef(strings.TrimPrefix(input, githubScheme))
+	parts := strings.SplitN(location, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || strings.Trim(parts[2], "/") == "" {
+		return nil, fmt.Errorf("invalid GitHub source %s (expected github://owner/repo/path@ref)", input)
+	}
+
+	return &githubSource{
+		Owner: parts[0],
+		Repo:  parts[1],
+		Path:  strings.Trim(parts[2], "/"),
+		Ref:   ref,
+	}, nil
+}
+
+// contentsURL returns the contents API URL of the file
+func (s *githubSource) contentsURL(apiURL string) string {
+	segments := strings.Split(s.Path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	u := fmt.Sprintf(
+		"%s/repos/%s/%s/contents/%s",
+		strings.TrimSuffix(apiURL, "/"),
+		url.PathEscape(s.Owner), url.PathEscape(s.Repo), strings.Join(segments, "/"),
+	)
+	if s.Ref != "" {
+		u += "?ref=" + url.QueryEscape(s.Ref)
+	}
+	return u
+}
+
+// githubToken returns the token for the contents API, preferring the github_token input
+func githubToken() string {
+	if token := os.Getenv("INPUT_GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+// loadFromGitHub loads a file through the GitHub contents API, authenticating with
+// the github_token input or GITHUB_TOKEN so private repositories work
+func loadFromGitHub(input string) (string, error) {
+	source, err := parseGitHubSource(input)
+	if err != nil {
+		return "", err
+	}
+
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", source.contentsURL(apiURL), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for %s: %w", input, err)
+	}
+	// The raw media type returns the file content instead of base64 encoded JSON
+	req.Header.Set("Accept", "application/vnd.github.raw+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token := githubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return fetchContent(req, input)
+}
+
+// loadFromGitRef loads a file from the local checkout at a git ref, like
+// "git show ref:path". The input format is gitref://path@ref and the ref defaults to HEAD.
+func loadFromGitRef(input string) (string, error) {
+	path, ref := splitRef(strings.TrimPrefix(input, gitRefScheme))
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if path == "" {
+		return "", fmt.Errorf("invalid git source %s (expected gitref://path@ref)", input)
+	}
+	// Refuse refs that git would parse as options
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref %q in %s", ref, input)
+	}
+
+	content, err := gitOutput("show", ref+":"+strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", input, err)
+	}
+	return content, nil
+}
+
+// gitOutput runs git in the workspace and returns its standard output
+func gitOutput(args ...string) (string, error) {
+	dir := os.Getenv("GITHUB_WORKSPACE")
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	// The checkout is usually owned by another user than the container user, so trust
+	// this directory only rather than every repository
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-c", "safe.directory=" + dir}, args...)...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseGitHubSource(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    githubSource
+		wantErr bool
+	}{
+		{
+			input: "github://org/prompts/review/system.md@v1.2.0",
+			want:  githubSource{Owner: "org", Repo: "prompts", Path: "review/system.md", Ref: "v1.2.0"},
+		},
+		{
+			input: "github://org/prompts/system.md",
+			want:  githubSource{Owner: "org", Repo: "prompts", Path: "system.md"},
+		},
+		{
+			input: "github://org/prompts/system.md@feature/new-prompts",
+			want:  githubSource{Owner: "org", Repo: "prompts", Path: "system.md", Ref: "feature/new-prompts"},
+		},
+		{input: "github://org/prompts", wantErr: true},
+		{input: "github://org/prompts/@main", wantErr: true},
+		{input: "github:///prompts/file.md", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseGitHubSource(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseGitHubSource(%q) expected error, got %+v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGitHubSource(%q) unexpected error: %v", tt.input, err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseGitHubSource(%q) = %+v, want %+v", tt.input, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadContentFromGitHub(t *testing.T) {
+	var gotPath, gotQuery, gotAuth, gotAccept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.EscapedPath(), r.URL.RawQuery
+		gotAuth, gotAccept = r.Header.Get("Authorization"), r.Header.Get("Accept")
+		if strings.Contains(r.URL.Path, "missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("You are a reviewer for {{.GITHUB_REPOSITORY}}"))
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_TOKEN", "ghs_fallback")
+	t.Setenv("INPUT_GITHUB_TOKEN", "ghs_input")
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+
+	content, err := LoadContent("github://org/private-prompts/review/system prompt.md@release/v2")
+	if err != nil {
+		t.Fatalf("LoadContent() unexpected error: %v", err)
+	}
+	if content != "You are a reviewer for {{.GITHUB_REPOSITORY}}" {
+		t.Errorf("LoadContent() = %q", content)
+	}
+	if gotPath != "/repos/org/private-prompts/contents/review/system%20prompt.md" {
+		t.Errorf("unexpected path: %s", gotPath)
+	}
+	if gotQuery != "ref=release%2Fv2" {
+		t.Errorf("unexpected query: %s", gotQuery)
+	}
+	if gotAuth != "Bearer ghs_input" {
+		t.Errorf("unexpected Authorization header: %q", gotAuth)
+	}
+	if gotAccept != "application/vnd.github.raw+json" {
+		t.Errorf("unexpected Accept header: %q", gotAccept)
+	}
+
+	// LoadPrompt renders the fetched content
+	t.Setenv("INPUT_GITHUB_TOKEN", "")
+	prompt, err := LoadPrompt("github://org/private-prompts/system.md")
+	if err != nil {
+		t.Fatalf("LoadPrompt() unexpected error: %v", err)
+	}
+	if prompt != "You are a reviewer for owner/repo" {
+		t.Errorf("LoadPrompt() = %q", prompt)
+	}
+	if gotAuth != "Bearer ghs_fallback" {
+		t.Errorf("expected GITHUB_TOKEN fallback, got %q", gotAuth)
+	}
+	if gotQuery != "" {
+		t.Errorf("expected no ref query, got %q", gotQuery)
+	}
+
+	_, err = LoadContent("github://org/private-prompts/missing.md@main")
+	if err == nil || !strings.Contains(err.Error(), "status code 404") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+// runGit runs a git command in dir, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestLoadContentFromGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := setupWorkspace(t, map[string]string{".github/prompts/review.md": "version one"})
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "first")
+	first := runGit(t, dir, "rev-parse", "HEAD")
+	runGit(t, dir, "tag", "v1")
+
+	writeTestFiles(t, dir, map[string]string{".github/prompts/review.md": "version two"})
+	runGit(t, dir, "commit", "-q", "-am", "second")
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr string
+	}{
+		{input: "gitref://.github/prompts/review.md@" + first, want: "version one"},
+		{input: "gitref://.github/prompts/review.md@v1", want: "version one"},
+		{input: "gitref://.github/prompts/review.md", want: "version two"},
+		{input: "gitref://.github/prompts/missing.md@v1", wantErr: "failed to read"},
+		{input: "gitref://.github/prompts/review.md@--output=/tmp/x", wantErr: "invalid git ref"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := LoadContent(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadContent() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadContent() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LoadContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,28 +3,105 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strings"
-	"time"
+	"sync"
 )
 
 // LoadContent intelligently loads content from text, file, or URL without template rendering
 // It detects the input type automatically:
 // - If starts with http:// or https:// -> loads from URL
+// - If starts with github:// -> loads from a GitHub repository via the contents API
+// - If starts with gitref:// -> loads from the local checkout at a git ref
+// - If starts with data: -> decodes the data URI
+// - If starts with env:// -> reads the environment variable
+// - If equals "-" -> reads standard input
 // - If starts with file:// or is a valid file path -> loads from file
 // - Otherwise -> returns as plain text
+// A "#sha256-<digest>" suffix pins the content to a digest, and remote content must be
+// signed when prompt_public_key is set. Both are verified before the content is returned.
 func LoadContent(input string) (string, error) {
+	return loadContentAs(sourceAuto, input)
+}
+
+// loadContentAs loads content like LoadContent, treating the input as the given
+// source kind instead of detecting it when kind is not sourceAuto
+func loadContentAs(kind, input string) (string, error) {
 	if input == "" {
 		return "", nil
 	}
 
-	// Determine source type and load content
+	source, pinned := splitIntegrity(input)
+	if kind == sourceAuto {
+		kind = detectSource(source)
+	}
+	content, err := loadSourceAs(kind, source)
+	if err != nil {
+		return "", err
+	}
+
+	if pinned != "" {
+		if err := verifyIntegrity(source, content, pinned); err != nil {
+			return "", err
+		}
+	}
+	if kind == sourceURL || kind == sourceGitHub {
+		if err := verifySignature(source, content); err != nil {
+			return "", err
+		}
+	}
+
+	return content, nil
+}
+
+// detectSource returns the source kind of an input
+func detectSource(input string) string {
 	switch {
 	case isURL(input):
-		return loadFromURL(input)
+		return sourceURL
+	case isGitHubSource(input):
+		return sourceGitHub
+	case isGitSource(input):
+		return sourceGit
+	case isStrictDataURI(input):
+		return sourceData
+	case isEnvSource(input):
+		return sourceEnv
+	case isStdinSource(input):
+		return sourceStdin
 	case isFilePath(input):
+		return sourceFile
+	default:
+		return sourceText
+	}
+}
+
+// loadSource loads content from text, file, or URL without any verification
+func loadSource(input string) (string, error) {
+	return loadSourceAs(detectSource(input), input)
+}
+
+// loadSourceAs loads content of the given source kind without any verification
+func loadSourceAs(kind, input string) (string, error) {
+	if !sourceMatches(kind, input) {
+		return "", fmt.Errorf("input is not a %s source (expected %s)", kind, sourceFormats[kind])
+	}
+
+	switch kind {
+	case sourceURL:
+		return loadFromURL(input)
+	case sourceGitHub:
+		return loadFromGitHub(input)
+	case sourceGit:
+		return loadFromGitRef(input)
+	case sourceData:
+		return loadFromDataURI(input)
+	case sourceEnv:
+		return loadFromEnv(input)
+	case sourceStdin:
+		return loadFromStdin()
+	case sourceFile:
 		return loadFromFile(input)
 	default:
 		// Return as plain text
@@ -33,38 +110,27 @@ func LoadContent(input string) (string, error) {
 }
 
 // LoadPrompt intelligently loads prompt content from text, file, or URL
-// It detects the input type automatically:
-// - If starts with http:// or https:// -> loads from URL
-// - If starts with file:// or is a valid file path -> loads from file
-// - Otherwise -> returns as plain text
+// It detects the input type the same way as LoadContent
 // After loading, it renders the content as a Go template with environment variables
 func LoadPrompt(input string) (string, error) {
-	if input == "" {
-		return "", nil
+	content, err := LoadContent(input)
+	if err != nil {
+		return "", err
 	}
+	return renderPrompt(content, false)
+}
 
-	var content string
-	var err error
-
-	// Determine source type and load content
-	switch {
-	case isURL(input):
-		content, err = loadFromURL(input)
-		if err != nil {
-			return "", err
-		}
-	case isFilePath(input):
-		content, err = loadFromFile(input)
-		if err != nil {
-			return "", err
-		}
-	default:
-		// Return as plain text
-		content = input
+// renderPrompt renders loaded prompt content as a Go template with environment variables,
+// failing on missing keys when strict is set
+func renderPrompt(content string, strict bool) (string, error) {
+	if content == "" {
+		return "", nil
 	}
 
+	_, span := startSpan(context.Background(), "render_prompt", spanKindInternal)
 	// Render template with environment variables
-	rendered, err := RenderTemplate(content)
+	rendered, err := RenderTemplateWithData(content, nil, strict)
+	span.finish(err)
 	if err != nil {
 		return "", fmt.Errorf("failed to render template: %w", err)
 	}
@@ -94,11 +160,6 @@ func isFilePath(input string) bool {
 
 // loadFromURL loads content from a URL
 func loadFromURL(url string) (string, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
 	// Create request with context
 	ctx := context.Background()
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -106,28 +167,7 @@ func loadFromURL(url string) (string, error) {
 		return "", fmt.Errorf("failed to create request for URL %s: %w", url, err)
 	}
 
-	// Add User-Agent header
-	req.Header.Set("User-Agent", "LLM-Action/1.0")
-
-	// Send request
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL %s: %w", url, err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL %s: status code %d", url, resp.StatusCode)
-	}
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response from URL %s: %w", url, err)
-	}
-
-	return string(body), nil
+	return fetchContent(req, url)
 }
 
 // loadFromFile loads content from a local file
@@ -143,3 +183,30 @@ func loadFromFile(path string) (string, error) {
 
 	return string(content), nil
 }
+
+// loadCache memoizes values loaded from an input string
+type loadCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]T
+}
+
+// get returns the cached value for input, calling load on the first request.
+// Failed loads are not cached.
+func (c *loadCache[T]) get(input string, load func(string) (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value, ok := c.entries[input]; ok {
+		return value, nil
+	}
+
+	value, err := load(input)
+	if err != nil {
+		return value, err
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]T)
+	}
+	c.entries[input] = value
+	return value, nil
+}
@@ -173,8 +173,8 @@ func TestLoadPrompt_URL(t *testing.T) {
 			server := httptest.NewServer(
 				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					// Check User-Agent header
-					if userAgent := r.Header.Get("User-Agent"); userAgent != "LLM-Action/1.0" {
-						t.Errorf("expected User-Agent 'LLM-Action/1.0', got %q", userAgent)
+					if userAgent := r.Header.Get("User-Agent"); userAgent != GetUserAgent() {
+						t.Errorf("expected User-Agent %q, got %q", GetUserAgent(), userAgent)
 					}
 
 					w.WriteHeader(tt.statusCode)
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Source kinds accepted by the prompt_source input
+const (
+	sourceAuto   = "auto"
+	sourceText   = "text"
+	sourceFile   = "file"
+	sourceURL    = "url"
+	sourceGitHub = "github"
+	sourceGit    = "gitref"
+	sourceData   = "data"
+	sourceEnv    = "env"
+	sourceStdin  = "stdin"
+	sourceList   = "list"
+)
+
+// sourceKinds lists the valid prompt_source values
+var sourceKinds = []string{
+	sourceAuto, sourceText, sourceFile, sourceURL, sourceGitHub, sourceGit, sourceData, sourceEnv, sourceStdin, sourceList,
+}
+
+// sourceFormats describes the input format of source kinds with a fixed prefix
+var sourceFormats = map[string]string{
+	sourceURL:    "an http:// or https:// URL",
+	sourceGitHub: "github://owner/repo/path@ref",
+	sourceGit:    "gitref://path@ref",
+	sourceData:   "a data: URI",
+	sourceEnv:    "env://VAR_NAME",
+	sourceStdin:  `"-"`,
+}
+
+const (
+	dataScheme = "data:"
+	envScheme  = "env://"
+)
+
+// stdin is read for the "-" source, replaceable in tests
+var stdin io.Reader = os.Stdin
+
+// stdinCache holds standard input, which can only be read once
+var stdinCache loadCache[string]
+
+// sourceMatches reports whether the input has the format of the source kind
+func sourceMatches(kind, input string) bool {
+	switch kind {
+	case sourceURL:
+		return isURL(input)
+	case sourceGitHub:
+		return isGitHubSource(input)
+	case sourceGit:
+		return isGitSource(input)
+	case sourceData:
+		return isDataURI(input)
+	case sourceEnv:
+		return isEnvSource(input)
+	case sourceStdin:
+		return isStdinSource(input)
+	default:
+		return true
+	}
+}
+
+// strictDataURI matches a well-formed RFC 2397 data URI: an optional media type with
+// parameters, an optional ";base64" and a payload without whitespace
+var strictDataURI = regexp.MustCompile(
+	`^data:(?:[\w!#$&^.+-]+/[\w!#$&^.+-]+)?(?:;[\w!#$&^.+-]+=[^;,\s]+)*(?:;base64)?,\S*$`,
+)
+
+// isDataURI checks if the input is a data URI such as data:text/plain;base64,SGVsbG8=
+func isDataURI(input string) bool {
+	return len(input) >= len(dataScheme) && strings.EqualFold(input[:len(dataScheme)], dataScheme)
+}
+
+// isStrictDataURI checks if the input is a well-formed data URI. Detection relies on it,
+// so plain text that merely starts with "Data:" is not decoded.
+func isStrictDataURI(input string) bool {
+	return strictDataURI.MatchString(input)
+}
+
+// loadFromDataURI decodes the data of a base64 or percent-encoded data URI
+func loadFromDataURI(input string) (string, error) {
+	meta, data, found := strings.Cut(input[len(dataScheme):], ",")
+	if !found {
+		return "", errors.New("invalid data URI: missing ','")
+	}
+
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+		if err != nil {
+			return "", fmt.Errorf("invalid data URI: %w", err)
+		}
+		return string(decoded), nil
+	}
+
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid data URI: %w", err)
+	}
+	return decoded, nil
+}
+
+// isEnvSource checks if the input references an environment variable as env://VAR_NAME
+func isEnvSource(input string) bool {
+	return strings.HasPrefix(input, envScheme)
+}
+
+// loadFromEnv reads an environment variable. Variables hidden from templates,
+// such as the API key, cannot be read either.
+func loadFromEnv(input string) (string, error) {
+	name := strings.TrimPrefix(input, envScheme)
+	if name == "" {
+		return "", fmt.Errorf("invalid env source %s (expected env://VAR_NAME)", input)
+	}
+	if !loadEnvFilter().allowed(name) {
+		return "", fmt.Errorf("environment variable %s is not exposed to prompts", name)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// isStdinSource checks if the input refers to standard input
+func isStdinSource(input string) bool {
+	return input == "-"
+}
+
+// loadFromStdin reads standard input once; later reads return the same content
+func loadFromStdin() (string, error) {
+	return stdinCache.get("-", func(string) (string, error) {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return string(content), nil
+	})
+}
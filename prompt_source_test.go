@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadContentSources(t *testing.T) {
+	t.Setenv("REVIEW_PROMPT_TEST", "Review this change")
+	t.Setenv("DEPLOY_TOKEN_TEST", "ghs_secret_value")
+	t.Setenv("DEPLOY_TOKEN", "ghs_secret_value")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "base64 data URI", input: "data:text/plain;base64,SGVsbG8sIHt7Lk5BTUV9fQ==", want: "Hello, {{.NAME}}"},
+		{name: "percent-encoded data URI", input: "data:,Hello%2C%20World", want: "Hello, World"},
+		{name: "data URI with charset", input: "data:text/markdown;charset=utf-8;base64,IyBUaXRsZQ==", want: "# Title"},
+		{name: "invalid base64", input: "data:;base64,!!!", wantErr: "invalid data URI"},
+		{name: "data URI without comma", input: "data:text/plain;base64", want: "data:text/plain;base64"},
+		{
+			name:  "prose starting with Data:",
+			input: "Data: revenue, costs and margin. Summarize the trend.",
+			want:  "Data: revenue, costs and margin. Summarize the trend.",
+		},
+		{name: "data URI with whitespace", input: "data:,Hello World", want: "data:,Hello World"},
+		{name: "env source", input: "env://REVIEW_PROMPT_TEST", want: "Review this change"},
+		{name: "unset env source", input: "env://MISSING_PROMPT_TEST", wantErr: "is not set"},
+		{name: "secret env source", input: "env://DEPLOY_TOKEN", wantErr: "is not exposed to prompts"},
+		{name: "empty env source", input: "env://", wantErr: "expected env://VAR_NAME"},
+		{name: "git protocol URL", input: "git://example.com/repo.git", want: "git://example.com/repo.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadContent(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadContent() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadContent() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LoadContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadContentStdin(t *testing.T) {
+	stdinCache = loadCache[string]{}
+	original := stdin
+	stdin = strings.NewReader("piped prompt\n")
+	defer func() {
+		stdin = original
+		stdinCache = loadCache[string]{}
+	}()
+
+	// Standard input is read once and reused
+	for range 2 {
+		got, err := LoadContent("-")
+		if err != nil {
+			t.Fatalf("LoadContent() unexpected error: %v", err)
+		}
+		if got != "piped prompt\n" {
+			t.Errorf("LoadContent() = %q, want %q", got, "piped prompt\n")
+		}
+	}
+}
+
+func TestLoadConfigWithPromptSource(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("file content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		source     string
+		input      string
+		system     string
+		wantInput  string
+		wantSystem string
+		wantErr    string
+	}{
+		{name: "auto loads existing file", input: "README", wantInput: "file content"},
+		{name: "text keeps file name", source: "text", input: "README", wantInput: "README"},
+		{
+			name: "per-input kind", source: "input_prompt=text, system_prompt=file", input: "README", system: "README",
+			wantInput: "README", wantSystem: "file content",
+		},
+		{
+			name: "per-input kind overrides bare kind", source: "file\ninput_prompt=text", input: "README", system: "README",
+			wantInput: "README", wantSystem: "file content",
+		},
+		{name: "file kind requires file", source: "file", input: "missing.md", wantErr: "failed to read file"},
+		{name: "url kind requires URL", source: "url", input: "README", wantErr: "input is not a url source"},
+		{name: "data kind", source: "data", input: "data:,Hello", wantInput: "Hello"},
+		{name: "data kind with spaces", source: "data", input: "data:,Hello World", wantInput: "Hello World"},
+		{name: "data kind without comma", source: "data", input: "data:text/plain;base64", wantErr: "missing ','"},
+		{name: "invalid kind", source: "ftp", input: "README", wantErr: "invalid prompt_source value"},
+		{name: "invalid input name", source: "model=text", input: "README", wantErr: "invalid prompt_source value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_API_KEY", "test-key")
+			t.Setenv("INPUT_PROMPT_SOURCE", tt.source)
+			t.Setenv("INPUT_INPUT_PROMPT", tt.input)
+			t.Setenv("INPUT_SYSTEM_PROMPT", tt.system)
+
+			config, err := LoadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+			if config.InputPrompt != tt.wantInput {
+				t.Errorf("InputPrompt = %q, want %q", config.InputPrompt, tt.wantInput)
+			}
+			if config.SystemPrompt != tt.wantSystem {
+				t.Errorf("SystemPrompt = %q, want %q", config.SystemPrompt, tt.wantSystem)
+			}
+		})
+	}
+}
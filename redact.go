@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// redactionRule replaces every match of a pattern with a placeholder of its category
+type redactionRule struct {
+	category string
+	re       *regexp.Regexp
+}
+
+// builtinRedactionRules cover credentials and personal data. Private key blocks come
+// first so the whole block is replaced rather than only its header.
+var builtinRedactionRules = func() []redactionRule {
+	rules := []redactionRule{
+		{"PRIVATE_KEY", regexp.MustCompile(
+			`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----[\s\S]*?-----END (?:[A-Z]+ )?PRIVATE KEY-----`,
+		)},
+	}
+	for _, p := range secretValuePatterns {
+		if p.name == "private key" {
+			continue
+		}
+		rules = append(rules, redactionRule{redactionCategory(p.name), p.re})
+	}
+	return append(rules,
+		redactionRule{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+		redactionRule{"EMAIL", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+		redactionRule{"PHONE", regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]\d{4}\b`)},
+	)
+}()
+
+// placeholderPattern matches placeholders produced by a redactor
+var placeholderPattern = regexp.MustCompile(`\[REDACTED_[A-Z0-9_]+_\d+\]`)
+
+// redactionCategory converts a pattern name such as "GitHub token" to "GITHUB_TOKEN"
+func redactionCategory(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, " ", "_"))
+}
+
+// redactor replaces secrets and personal data with stable placeholders, so the same
+// value always maps to the same placeholder, and can restore them afterwards
+type redactor struct {
+	rules        []redactionRule
+	placeholders map[string]string // original value -> placeholder
+	originals    map[string]string // placeholder -> original value
+	counters     map[string]int
+	count        int
+}
+
+// newRedactor returns a redactor for the configured rules, or nil when redaction is disabled.
+// A nil redactor leaves messages and responses unchanged.
+func newRedactor(config *Config) *redactor {
+	var rules []redactionRule
+	if config.Redact {
+		// Exact secret values first, then the generic patterns
+		secrets := append([]string{config.APIKey, config.JudgeAPIKey}, hiddenEnvValues()...)
+		for _, value := range secrets {
+			if value != "" {
+				rules = append(rules, redactionRule{"SECRET", regexp.MustCompile(regexp.QuoteMeta(value))})
+			}
+		}
+		rules = append(rules, builtinRedactionRules...)
+	}
+	for _, re := range config.RedactPatterns {
+		rules = append(rules, redactionRule{"CUSTOM", re})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return &redactor{
+		rules:        rules,
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		counters:     make(map[string]int),
+	}
+}
+
+// placeholder returns the placeholder for a value, allocating one on first use
+func (r *redactor) placeholder(category, value string) string {
+	r.count++
+	if p, ok := r.placeholders[value]; ok {
+		return p
+	}
+
+	r.counters[category]++
+	p := fmt.Sprintf("[REDACTED_%s_%d]", category, r.counters[category])
+	r.placeholders[value] = p
+	r.originals[p] = value
+	return p
+}
+
+// reservePlaceholders makes new placeholders skip the numbers of placeholders already
+// present in the messages, so a value is never given the placeholder of another one
+func (r *redactor) reservePlaceholders(messages []openai.ChatCompletionMessage) {
+	if r == nil {
+		return
+	}
+
+	for _, msg := range messages {
+		for _, p := range placeholderPattern.FindAllString(msg.Content, -1) {
+			name := strings.TrimSuffix(strings.TrimPrefix(p, "[REDACTED_"), "]")
+			idx := strings.LastIndex(name, "_")
+			n, err := strconv.Atoi(name[idx+1:])
+			if err == nil && n > r.counters[name[:idx]] {
+				r.counters[name[:idx]] = n
+			}
+		}
+	}
+}
+
+// redact replaces rule matches in text with placeholders
+func (r *redactor) redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	for _, rule := range r.rules {
+		text = replaceOutsidePlaceholders(text, func(segment string) string {
+			return rule.re.ReplaceAllStringFunc(segment, func(match string) string {
+				return r.placeholder(rule.category, match)
+			})
+		})
+	}
+	return text
+}
+
+// replaceOutsidePlaceholders applies replace to the parts of text between placeholders,
+// so later rules cannot match inside placeholders of earlier ones
+func replaceOutsidePlaceholders(text string, replace func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(replace(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(replace(text[last:]))
+	return b.String()
+}
+
+// redactMessages returns a copy of the messages with their content redacted
+func (r *redactor) redactMessages(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if r == nil {
+		return messages
+	}
+
+	redacted := make([]openai.ChatCompletionMessage, len(messages))
+	for i, msg := range messages {
+		msg.Content = r.redact(msg.Content)
+		redacted[i] = msg
+	}
+	return redacted
+}
+
+// restore replaces placeholders in text with their original values
+func (r *redactor) restore(text string) string {
+	if r == nil || len(r.originals) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, len(r.originals)*2)
+	for p, value := range r.originals {
+		pairs = append(pairs, p, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Count returns the number of values replaced so far
+func (r *redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	return r.count
+}
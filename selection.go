@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Candidate selection strategies used when more than one completion is requested
+const (
+	SelectionFirst        = "first"
+	SelectionMajorityVote = "majority-vote"
+	SelectionLongest      = "longest"
+	SelectionJudge        = "judge"
+)
+
+// selectionStrategies lists all supported selection strategies in documentation order
+var selectionStrategies = []string{
+	SelectionFirst,
+	SelectionMajorityVote,
+	SelectionLongest,
+	SelectionJudge,
+}
+
+// judgeSelectionTool is the internal function schema the judge model uses to pick a candidate
+var judgeSelectionTool = ToolMeta{
+	Name:        "select_candidate",
+	Description: "Select the best candidate response for the request",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"index": map[string]any{
+				"type":        "integer",
+				"description": "Zero-based index of the best candidate",
+			},
+			"reason": map[string]any{
+				"type":        "string",
+				"description": "Short explanation of why this candidate is the best",
+			},
+		},
+		"required": []string{"index", "reason"},
+	},
+}
+
+const judgeSelectionPrompt = `You are an impartial judge. You will be given a request and several candidate responses.
+Select the single candidate that best satisfies the request in terms of correctness, completeness and clarity.
+Call the select_candidate function with the zero-based index of the best candidate.`
+
+// selectionResult describes the candidate picked by a selection strategy
+type selectionResult struct {
+	Index  int
+	Votes  int
+	Reason string
+	// Usage holds tokens spent by the selection itself (judge strategy only)
+	Usage openai.Usage
+}
+
+// extractCandidates extracts the response content from every choice in the API response
+func extractCandidates(
+	resp openai.ChatCompletionResponse,
+	toolMeta *ToolMeta,
+	debug bool,
+) ([]string, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from LLM")
+	}
+
+	candidates := make([]string, 0, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		content, err := extractChoice(choice, toolMeta, debug)
+		if err != nil {
+			return nil, fmt.Errorf("choice %d: %w", i, err)
+		}
+		candidates = append(candidates, content)
+	}
+
+	return candidates, nil
+}
+
+// selectCandidate picks one of the candidates according to the configured strategy.
+// The client is only used by the judge strategy and should target the judge model.
+func selectCandidate(
+	ctx context.Context,
+	client *openai.Client,
+	config *Config,
+	messages []openai.ChatCompletionMessage,
+	candidates []string,
+	structured bool,
+) (selectionResult, error) {
+	if len(candidates) == 0 {
+		return selectionResult{}, fmt.Errorf("no candidates to select from")
+	}
+	if len(candidates) == 1 {
+		return selectionResult{Index: 0, Votes: 1}, nil
+	}
+
+	switch config.SelectionStrategy {
+	case "", SelectionFirst:
+		return selectionResult{Index: 0, Votes: 1}, nil
+	case SelectionMajorityVote:
+		return selectMajority(candidates, structured), nil
+	case SelectionLongest:
+		return selectLongest(candidates), nil
+	case SelectionJudge:
+		return selectByJudge(ctx, client, config, messages, candidates)
+	default:
+		return selectionResult{}, fmt.Errorf("unknown selection strategy: %s", config.SelectionStrategy)
+	}
+}
+
+// selectMajority implements self-consistency voting: candidates are grouped by their
+// normalized form and the earliest candidate of the largest group wins
+func selectMajority(candidates []string, structured bool) selectionResult {
+	counts := make(map[string]int, len(candidates))
+	first := make(map[string]int, len(candidates))
+	for i, c := range candidates {
+		key := normalizeCandidate(c, structured)
+		if _, ok := first[key]; !ok {
+			first[key] = i
+		}
+		counts[key]++
+	}
+
+	best := selectionResult{Index: 0, Votes: 0}
+	for key, votes := range counts {
+		idx := first[key]
+		if votes > best.Votes || (votes == best.Votes && idx < best.Index) {
+			best = selectionResult{Index: idx, Votes: votes}
+		}
+	}
+
+	return best
+}
+
+// normalizeCandidate returns a comparison key for majority voting.
+// Tool call arguments are compared as canonical JSON, plain content is compared
+// case-insensitively with collapsed whitespace.
+func normalizeCandidate(s string, structured bool) string {
+	if structured {
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err == nil {
+			// json.Marshal sorts map keys, giving a canonical form
+			if b, err := json.Marshal(v); err == nil {
+				return string(b)
+			}
+		}
+	}
+
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// selectLongest picks the candidate with the most characters, preferring the earliest on ties
+func selectLongest(candidates []string) selectionResult {
+	best, bestLen := 0, -1
+	for i, c := range candidates {
+		if n := utf8.RuneCountInString(c); n > bestLen {
+			best, bestLen = i, n
+		}
+	}
+
+	return selectionResult{Index: best, Votes: 1}
+}
+
+// selectByJudge asks the model to pick the best candidate via a forced function call
+func selectByJudge(
+	ctx context.Context,
+	client *openai.Client,
+	config *Config,
+	messages []openai.ChatCompletionMessage,
+	candidates []string,
+) (selectionResult, error) {
+	if client == nil {
+		return selectionResult{}, fmt.Errorf("judge selection requires a client")
+	}
+
+	judgeMessages, err := prepareJudgeMessages(config, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: judgeSelectionPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: buildJudgeSelectionInput(messages, candidates)},
+	})
+	if err != nil {
+		return selectionResult{}, err
+	}
+
+	judgeConfig := config.judgeConfig()
+	req := buildChatRequest(judgeConfig, judgeMessages, &judgeSelectionTool)
+	start := time.Now()
+	resp, err := tracedChatCompletion(ctx, client, judgeConfig, req, auditKindJudgeSelection)
+	logCompletion(judgeConfig, auditKindJudgeSelection, resp, time.Since(start), err)
+	if auditErr := auditCompletion(config, auditKindJudgeSelection, req, resp, err); auditErr != nil {
+		return selectionResult{}, auditErr
+	}
+	if err != nil {
+		return selectionResult{}, fmt.Errorf("judge selection error: %w", err)
+	}
+
+	args, err := extractResponse(resp, &judgeSelectionTool, config.Debug)
+	if err != nil {
+		return selectionResult{}, fmt.Errorf("judge selection: %w", err)
+	}
+
+	var choice struct {
+		Index  int    `json:"index"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(args), &choice); err != nil {
+		return selectionResult{}, fmt.Errorf("failed to parse judge selection: %w", err)
+	}
+	if choice.Index < 0 || choice.Index >= len(candidates) {
+		return selectionResult{}, fmt.Errorf(
+			"judge selected candidate %d, but only %d candidates exist",
+			choice.Index, len(candidates),
+		)
+	}
+
+	return selectionResult{
+		Index:  choice.Index,
+		Votes:  1,
+		Reason: choice.Reason,
+		Usage:  resp.Usage,
+	}, nil
+}
+
+// buildJudgeSelectionInput formats the original conversation and the candidates for the judge
+func buildJudgeSelectionInput(messages []openai.ChatCompletionMessage, candidates []string) string {
+	var b strings.Builder
+
+	b.WriteString("## Request\n\n")
+	writeConversation(&b, messages)
+
+	b.WriteString("## Candidates\n")
+	for i, c := range candidates {
+		fmt.Fprintf(&b, "\n### Candidate %d\n\n%s\n", i, c)
+	}
+
+	return b.String()
+}
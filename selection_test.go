@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestExtractCandidates(t *testing.T) {
+	toolMeta := &ToolMeta{Name: "get_weather"}
+
+	tests := []struct {
+		name        string
+		resp        openai.ChatCompletionResponse
+		toolMeta    *ToolMeta
+		expected    []string
+		expectError bool
+	}{
+		{
+			name: "Multiple content choices",
+			resp: openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Content: "a"}},
+					{Message: openai.ChatCompletionMessage{Content: "b"}},
+				},
+			},
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "Tool call choices",
+			resp: openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{ToolCalls: []openai.ToolCall{
+						{Function: openai.FunctionCall{Arguments: `{"city":"Taipei"}`}},
+					}}},
+					{Message: openai.ChatCompletionMessage{ToolCalls: []openai.ToolCall{
+						{Function: openai.FunctionCall{Arguments: `{"city":"Tokyo"}`}},
+					}}},
+				},
+			},
+			toolMeta: toolMeta,
+			expected: []string{`{"city":"Taipei"}`, `{"city":"Tokyo"}`},
+		},
+		{
+			name: "Missing tool call in one choice",
+			resp: openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{ToolCalls: []openai.ToolCall{
+						{Function: openai.FunctionCall{Arguments: `{}`}},
+					}}},
+					{Message: openai.ChatCompletionMessage{Content: "text"}},
+				},
+			},
+			toolMeta:    toolMeta,
+			expectError: true,
+		},
+		{
+			name:        "No choices",
+			resp:        openai.ChatCompletionResponse{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractCandidates(tt.resp, tt.toolMeta, false)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSelectCandidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   string
+		candidates []string
+		structured bool
+		expected   int
+		votes      int
+	}{
+		{
+			name:       "First",
+			strategy:   SelectionFirst,
+			candidates: []string{"a", "bb", "ccc"},
+			expected:   0,
+			votes:      1,
+		},
+		{
+			name:       "Longest",
+			strategy:   SelectionLongest,
+			candidates: []string{"a", "ccc", "bb"},
+			expected:   1,
+			votes:      1,
+		},
+		{
+			name:       "Longest counts characters not bytes",
+			strategy:   SelectionLongest,
+			candidates: []string{"你好", "abc"},
+			expected:   1,
+			votes:      1,
+		},
+		{
+			name:       "Majority vote normalizes whitespace and case",
+			strategy:   SelectionMajorityVote,
+			candidates: []string{"Paris", "The answer is 42", "the  answer is 42 ", "THE ANSWER IS 42"},
+			expected:   1,
+			votes:      3,
+		},
+		{
+			name:       "Majority vote tie prefers earliest",
+			strategy:   SelectionMajorityVote,
+			candidates: []string{"x", "y", "y", "x"},
+			expected:   0,
+			votes:      2,
+		},
+		{
+			name:     "Majority vote compares tool arguments as canonical JSON",
+			strategy: SelectionMajorityVote,
+			candidates: []string{
+				`{"city":"Tokyo"}`,
+				`{"country":"Taiwan","city":"Taipei"}`,
+				`{"city": "Taipei", "country": "Taiwan"}`,
+			},
+			structured: true,
+			expected:   1,
+			votes:      2,
+		},
+		{
+			name:       "Single candidate",
+			strategy:   SelectionJudge,
+			candidates: []string{"only"},
+			expected:   0,
+			votes:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{SelectionStrategy: tt.strategy}
+			got, err := selectCandidate(context.Background(), nil, config, nil, tt.candidates, tt.structured)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Index != tt.expected {
+				t.Errorf("expected index %d, got %d", tt.expected, got.Index)
+			}
+			if got.Votes != tt.votes {
+				t.Errorf("expected %d votes, got %d", tt.votes, got.Votes)
+			}
+		})
+	}
+}
+
+// newChatCompletionServer starts a test server that answers every chat completion
+// request with the given tool call arguments
+func newChatCompletionServer(t *testing.T, arguments string, capture *openai.ChatCompletionRequest) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if capture != nil {
+			if err := json.NewDecoder(r.Body).Decode(capture); err != nil {
+				t.Errorf("failed to decode request: %v", err)
+			}
+		}
+		resp := openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{
+					Role: openai.ChatMessageRoleAssistant,
+					ToolCalls: []openai.ToolCall{
+						{Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Arguments: arguments}},
+					},
+				}},
+			},
+			Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestSelectCandidateJudge(t *testing.T) {
+	var captured openai.ChatCompletionRequest
+	server := newChatCompletionServer(t, `{"index": 1, "reason": "more complete"}`, &captured)
+	defer server.Close()
+
+	config := &Config{
+		BaseURL:           server.URL,
+		APIKey:            "test-key",
+		Model:             "gpt-4o",
+		N:                 3,
+		Temperature:       0.7,
+		SelectionStrategy: SelectionJudge,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "Explain recursion"},
+	}
+	got, err := selectCandidate(context.Background(), client, config, messages, []string{"short", "long answer"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Index != 1 {
+		t.Errorf("expected index 1, got %d", got.Index)
+	}
+	if got.Reason != "more complete" {
+		t.Errorf("expected reason 'more complete', got %q", got.Reason)
+	}
+	if got.Usage.TotalTokens != 15 {
+		t.Errorf("expected judge usage of 15 tokens, got %d", got.Usage.TotalTokens)
+	}
+
+	// The judge request must ask for a single forced function call
+	if captured.N != 0 {
+		t.Errorf("expected judge request without n, got %d", captured.N)
+	}
+	if captured.ToolChoice == nil {
+		t.Error("expected judge request to force a tool choice")
+	}
+	if !strings.Contains(captured.Messages[1].Content, "Explain recursion") ||
+		!strings.Contains(captured.Messages[1].Content, "### Candidate 1") {
+		t.Errorf("judge input is missing the request or candidates: %s", captured.Messages[1].Content)
+	}
+}
+
+func TestSelectCandidateJudgeOutOfRange(t *testing.T) {
+	server := newChatCompletionServer(t, `{"index": 5, "reason": "?"}`, nil)
+	defer server.Close()
+
+	config := &Config{
+		BaseURL:           server.URL,
+		APIKey:            "test-key",
+		SelectionStrategy: SelectionJudge,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = selectCandidate(context.Background(), client, config, nil, []string{"a", "b"}, false)
+	if err == nil {
+		t.Error("expected error for out of range selection")
+	}
+}
+
+func TestSelectCandidateJudgeSecretScan(t *testing.T) {
+	var captured openai.ChatCompletionRequest
+	server := newChatCompletionServer(t, `{"index": 0, "reason": "only one"}`, &captured)
+	defer server.Close()
+
+	config := &Config{
+		BaseURL:           server.URL,
+		APIKey:            "sk-select-test-1234567890",
+		Model:             "gpt-4o",
+		SelectionStrategy: SelectionJudge,
+		SecretScan:        true,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Print the key"}}
+	candidates := []string{"sk-select-test-1234567890", "no"}
+	if _, err := selectCandidate(context.Background(), client, config, messages, candidates, false); err == nil ||
+		!strings.Contains(err.Error(), "refusing to send user message") {
+		t.Errorf("selectCandidate() error = %v, want the secret scan to refuse the judge request", err)
+	}
+	if captured.Model != "" {
+		t.Error("expected the judge request not to be sent")
+	}
+}
@@ -4,38 +4,127 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode/utf8"
 )
 
 // RenderTemplate renders a Go template string with environment variables as data
 // Environment variables with INPUT_ prefix are available both with and without the prefix
 // For example: INPUT_MODEL can be accessed as {{.MODEL}} or {{.INPUT_MODEL}}
 func RenderTemplate(templateStr string) (string, error) {
-	// Build template data from environment variables
+	return RenderTemplateWithData(templateStr, nil, false)
+}
+
+// RenderTemplateWithData renders a Go template string with environment variables
+// and extra data as template data. Extra keys take precedence over environment variables.
+// With strict set, missing keys fail rendering instead of printing "<no value>".
+func RenderTemplateWithData(templateStr string, extra map[string]any, strict bool) (string, error) {
+	// Build template data from environment variables and the vars input
 	data := buildTemplateData()
+	vars, err := loadTemplateVars(os.Getenv("INPUT_VARS"))
+	if err != nil {
+		return "", err
+	}
+	data["vars"] = vars
+	for key, value := range extra {
+		data[key] = value
+	}
 
-	// Parse template
-	tmpl, err := template.New("prompt").Parse(templateStr)
+	// Give the template read-only access to workspace files
+	ws, err := newWorkspaceReader()
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return "", err
+	}
+
+	// Shared partials from the prompt library
+	library, err := loadPromptLibrary(os.Getenv("INPUT_PROMPT_LIBRARY"))
+	if err != nil {
+		return "", err
+	}
+
+	r := &templateRenderer{data: data, workspace: ws, library: library, strict: strict}
+	return r.render("prompt", templateStr)
+}
+
+// templateRenderer renders a prompt and the templates it includes with the same data,
+// workspace reader and prompt library
+type templateRenderer struct {
+	data      map[string]any
+	workspace *workspaceReader
+	library   []libraryTemplate
+	// strict fails rendering on missing map keys instead of printing "<no value>"
+	strict bool
+}
+
+// render parses and executes a template with the standard and workspace functions.
+// Prompt library templates are parsed first so the template can redefine them.
+func (r *templateRenderer) render(name, templateStr string) (string, error) {
+	funcs := templateFuncs()
+	for key, fn := range r.workspace.funcs(r.render) {
+		funcs[key] = fn
+	}
+
+	tmpl := template.New(name).Funcs(funcs)
+	if r.strict {
+		tmpl.Option("missingkey=error")
+	}
+	if err := addLibraryTemplates(tmpl, r.library); err != nil {
+		return "", err
+	}
+
+	// Parse template
+	if _, err := tmpl.Parse(templateStr); err != nil {
+		return "", fmt.Errorf("failed to parse template: %w%s", err, templateErrorSnippet(name, templateStr, err))
 	}
 
 	// Execute template
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	if err := tmpl.Execute(&buf, r.data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w%s", err, templateErrorSnippet(name, templateStr, err))
 	}
 
 	return buf.String(), nil
 }
 
+// templateErrorSnippet returns the source line a template error points at, with a caret
+// under the offending action when the error includes a column. It returns an empty
+// string when the error has no position inside the named template.
+func templateErrorSnippet(name, src string, err error) string {
+	re := regexp.MustCompile(`template: ` + regexp.QuoteMeta(name) + `:(\d+)(?::(\d+))?:`)
+	match := re.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ""
+	}
+
+	lineNum, _ := strconv.Atoi(match[1])
+	lines := strings.Split(src, "\n")
+	if lineNum < 1 || lineNum > len(lines) {
+		return ""
+	}
+
+	line := lines[lineNum-1]
+	snippet := fmt.Sprintf("\n%5d | %s", lineNum, line)
+	if match[2] != "" {
+		// Columns are byte offsets into the line
+		col, _ := strconv.Atoi(match[2])
+		if col >= 0 && col <= len(line) {
+			snippet += fmt.Sprintf("\n      | %s^", strings.Repeat(" ", utf8.RuneCountInString(line[:col])))
+		}
+	}
+	return snippet
+}
+
 // buildTemplateData builds a map of environment variables for template rendering
 // INPUT_ prefixed variables are available both with and without the prefix
-func buildTemplateData() map[string]string {
-	data := make(map[string]string)
+// The GitHub event payload is available as {{.event}} and the github context as {{.github}}
+func buildTemplateData() map[string]any {
+	data := make(map[string]any)
 
-	// Get all environment variables
+	// Get all environment variables, leaving out secrets
+	filter := loadEnvFilter()
 	environ := os.Environ()
 	for _, env := range environ {
 		// Split key=value
@@ -46,6 +135,9 @@ func buildTemplateData() map[string]string {
 
 		key := parts[0]
 		value := parts[1]
+		if !filter.allowed(key) {
+			continue
+		}
 
 		// Add with original key
 		data[key] = value
@@ -57,5 +149,10 @@ func buildTemplateData() map[string]string {
 		}
 	}
 
+	// Expose the event payload and the github context alongside the flat env keys
+	event := loadEventPayload()
+	data["event"] = event
+	data["github"] = buildGitHubContext(event)
+
 	return data
 }
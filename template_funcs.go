@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// templateFuncs returns the functions available to prompt templates.
+// Functions take the piped value as their last argument so they can be chained,
+// e.g. {{ .diff | truncate 2000 | indent 4 }}.
+// The set is intentionally limited to pure data helpers: nothing here can execute
+// commands or reach the network.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// String helpers
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      titleCase,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       joinList,
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, max(count, 0)) },
+		"quote":      strconv.Quote,
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"truncate":   truncate,
+
+		// Regular expressions
+		"regexMatch":   regexMatch,
+		"regexFind":    regexFind,
+		"regexReplace": regexReplace,
+
+		// Encoding
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"fromJson":     fromJSON,
+		"toYaml":       toYAML,
+
+		// Defaults and validation
+		"default":  defaultValue,
+		"required": required,
+		"empty":    isEmpty,
+
+		// Environment
+		"env": templateEnv,
+
+		// Dates
+		"now":  time.Now,
+		"date": formatDate,
+
+		// Lists
+		"list":      func(items ...any) []any { return items },
+		"first":     first,
+		"last":      last,
+		"uniq":      uniq,
+		"sortAlpha": sortAlpha,
+		"has":       has,
+	}
+}
+
+// titleCase upper-cases the first letter of every word
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = strings.ToUpper(string(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// joinList joins any list of values with the separator
+func joinList(sep string, list any) (string, error) {
+	items, err := toList(list)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// indent prefixes every non-empty line with the given number of spaces
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", max(spaces, 0))
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate shortens s to at most length characters
+func truncate(length int, s string) string {
+	if length < 0 || utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length])
+}
+
+// regexMatch reports whether s matches the pattern
+func regexMatch(pattern, s string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+// regexFind returns the first match of the pattern in s
+func regexFind(pattern, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(s), nil
+}
+
+// regexReplace replaces all matches of the pattern in s; the replacement may use $1 style groups
+func regexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+// toJSON encodes v as compact JSON
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// toPrettyJSON encodes v as indented JSON
+func toPrettyJSON(v any) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// fromJSON decodes a JSON string
+func fromJSON(s string) (any, error) {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// toYAML encodes v as YAML without the trailing newline
+func toYAML(v any) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// defaultValue returns def when value is empty
+func defaultValue(def, value any) any {
+	if isEmpty(value) {
+		return def
+	}
+	return value
+}
+
+// required fails rendering with msg when value is empty
+func required(msg string, value any) (any, error) {
+	if isEmpty(value) {
+		return nil, errors.New(msg)
+	}
+	return value, nil
+}
+
+// isEmpty reports whether v is nil or the zero value of its type, or an empty collection
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+// formatDate formats a time with a Go layout. The value can be a time.Time,
+// an RFC 3339 string or Unix seconds.
+func formatDate(layout string, value any) (string, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		t = parsed
+	case int:
+		t = time.Unix(int64(v), 0).UTC()
+	case int64:
+		t = time.Unix(v, 0).UTC()
+	case float64:
+		t = time.Unix(int64(v), 0).UTC()
+	default:
+		return "", fmt.Errorf("date: unsupported value of type %T", value)
+	}
+	return t.Format(layout), nil
+}
+
+// toList converts any slice or array to []any
+func toList(list any) ([]any, error) {
+	if list == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", list)
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// first returns the first element of a list, or nil when empty
+func first(list any) (any, error) {
+	items, err := toList(list)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// last returns the last element of a list, or nil when empty
+func last(list any) (any, error) {
+	items, err := toList(list)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[len(items)-1], nil
+}
+
+// uniq removes duplicate elements while keeping the first occurrence
+func uniq(list any) ([]any, error) {
+	items, err := toList(list)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(items))
+	out := make([]any, 0, len(items))
+	for _, item := range items {
+		key := canonicalJSON(item)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// sortAlpha sorts the elements of a list by their string form
+func sortAlpha(list any) ([]string, error) {
+	items, err := toList(list)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = fmt.Sprint(item)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// has reports whether the list contains the item
+func has(item, list any) (bool, error) {
+	items, err := toList(list)
+	if err != nil {
+		return false, err
+	}
+	for _, candidate := range items {
+		if reflect.DeepEqual(candidate, item) || canonicalJSON(candidate) == canonicalJSON(item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	os.Setenv("TEMPLATE_FUNCS_TEST", "from-env")
+	defer os.Unsetenv("TEMPLATE_FUNCS_TEST")
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		// String helpers
+		{"upper", `{{ "hello" | upper }}`, "HELLO"},
+		{"lower", `{{ "HeLLo" | lower }}`, "hello"},
+		{"title", `{{ "hello big world" | title }}`, "Hello Big World"},
+		{"trim", `{{ "  padded  " | trim }}`, "padded"},
+		{"trimPrefix", `{{ "refs/heads/main" | trimPrefix "refs/heads/" }}`, "main"},
+		{"trimSuffix", `{{ "report.md" | trimSuffix ".md" }}`, "report"},
+		{"replace", `{{ "a-b-c" | replace "-" "_" }}`, "a_b_c"},
+		{"contains", `{{ if "feature/login" | contains "feature" }}yes{{ end }}`, "yes"},
+		{"hasPrefix", `{{ "v1.2.3" | hasPrefix "v" }}`, "true"},
+		{"hasSuffix", `{{ "main.go" | hasSuffix ".go" }}`, "true"},
+		{"split and join", `{{ "a,b,c" | split "," | join " + " }}`, "a + b + c"},
+		{"repeat", `{{ "=" | repeat 3 }}`, "==="},
+		{"quote", `{{ "say \"hi\"" | quote }}`, `"say \"hi\""`},
+		{"indent", `{{ "line1\nline2" | indent 2 }}`, "  line1\n  line2"},
+		{"indent skips empty lines", `{{ "a\n\nb" | indent 2 }}`, "  a\n\n  b"},
+		{"nindent", `key:{{ "value" | nindent 2 }}`, "key:\n  value"},
+		{"truncate", `{{ "abcdef" | truncate 3 }}`, "abc"},
+		{"truncate counts characters", `{{ "你好世界" | truncate 2 }}`, "你好"},
+		{"truncate shorter string", `{{ "ab" | truncate 10 }}`, "ab"},
+
+		// Regular expressions
+		{"regexMatch", `{{ "PROJ-123" | regexMatch "^[A-Z]+-[0-9]+$" }}`, "true"},
+		{"regexFind", `{{ "fixes #42 and #7" | regexFind "#[0-9]+" }}`, "#42"},
+		{"regexReplace", `{{ "2024-01-31" | regexReplace "(\\d+)-(\\d+)-(\\d+)" "$3/$2/$1" }}`, "31/01/2024"},
+
+		// Encoding
+		{"toJson", `{{ list "a" 1 true | toJson }}`, `["a",1,true]`},
+		{"toPrettyJson", `{{ fromJson "{\"a\":1}" | toPrettyJson }}`, "{\n  \"a\": 1\n}"},
+		{"fromJson field", `{{ (fromJson "{\"name\":\"llm\"}").name }}`, "llm"},
+		{"toYaml", `{{ fromJson "{\"b\":[1,2],\"a\":\"x\"}" | toYaml }}`, "a: x\nb:\n    - 1\n    - 2"},
+
+		// Defaults and validation
+		{"default on empty", `{{ "" | default "fallback" }}`, "fallback"},
+		{"default on missing key", `{{ .NOT_SET_ANYWHERE | default "fallback" }}`, "fallback"},
+		{"default keeps value", `{{ "set" | default "fallback" }}`, "set"},
+		{"required with value", `{{ "present" | required "value is required" }}`, "present"},
+		{"empty", `{{ if empty "" }}empty{{ end }}`, "empty"},
+
+		// Environment
+		{"env", `{{ env "TEMPLATE_FUNCS_TEST" }}`, "from-env"},
+
+		// Dates
+		{"date from RFC 3339", `{{ "2024-03-05T10:00:00Z" | date "Jan 2, 2006" }}`, "Mar 5, 2024"},
+		{"date from unix seconds", `{{ 0 | date "2006-01-02" }}`, "1970-01-01"},
+		{"now", `{{ now | date "2006" | len }}`, "4"},
+
+		// Lists
+		{"first", `{{ list "a" "b" | first }}`, "a"},
+		{"last", `{{ list "a" "b" | last }}`, "b"},
+		{"uniq", `{{ list "a" "b" "a" | uniq | join "," }}`, "a,b"},
+		{"sortAlpha", `{{ list "pear" "apple" "fig" | sortAlpha | join "," }}`, "apple,fig,pear"},
+		{"has", `{{ list "bug" "docs" | has "docs" }}`, "true"},
+		{"first of empty list", `{{ list | first }}`, "<no value>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("RenderTemplate() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		errMsg   string
+	}{
+		{"required without value", `{{ "" | required "summary is required" }}`, "summary is required"},
+		{"invalid regex", `{{ "x" | regexMatch "(" }}`, "error parsing regexp"},
+		{"invalid JSON", `{{ fromJson "{" }}`, "unexpected end of JSON input"},
+		{"invalid date", `{{ "yesterday" | date "2006" }}`, "date:"},
+		{"join on non-list", `{{ 42 | join "," }}`, "expected a list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderTemplate(tt.template)
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncsCannotExecuteCommands(t *testing.T) {
+	forbidden := []string{"exec", "shell", "sh", "cmd", "command", "run", "system", "spawn", "http", "fetch"}
+
+	funcs := templateFuncs()
+	for _, name := range forbidden {
+		if _, ok := funcs[name]; ok {
+			t.Errorf("template function %q must not be available", name)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// libraryTemplateExt is the extension of template files loaded from a prompt library
+const libraryTemplateExt = ".tmpl"
+
+// libraryTemplate is a named template file from a prompt library
+type libraryTemplate struct {
+	Name    string
+	Content string
+}
+
+// promptLibraryCache holds loaded prompt libraries keyed by the prompt_library input,
+// so URL bundles are fetched once even when many prompts are rendered
+var promptLibraryCache loadCache[[]libraryTemplate]
+
+// loadPromptLibrary loads the templates of every source listed in the prompt_library input.
+// Sources are separated by newlines and can be a directory, a template file, a .tar.gz/.tgz
+// or .zip archive, or a URL, github:// or gitref:// reference to a template file or archive.
+func loadPromptLibrary(input string) ([]libraryTemplate, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+
+	return promptLibraryCache.get(input, func(input string) ([]libraryTemplate, error) {
+		var templates []libraryTemplate
+		for _, source := range strings.Split(input, "\n") {
+			source = strings.TrimSpace(source)
+			if source == "" {
+				continue
+			}
+
+			loaded, err := loadLibrarySource(source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load prompt_library %s: %w", source, err)
+			}
+			templates = append(templates, loaded...)
+		}
+		return templates, nil
+	})
+}
+
+// loadLibrarySource loads the templates of a single prompt library source
+func loadLibrarySource(source string) ([]libraryTemplate, error) {
+	location, pinned := splitIntegrity(source)
+	if isURL(location) || isGitHubSource(location) || isGitSource(location) || pinned != "" {
+		content, err := LoadContent(source)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore any query string or ref when detecting the bundle format
+		name := strings.SplitN(location, "?", 2)[0]
+		if isGitHubSource(location) || isGitSource(location) {
+			name, _ = splitRef(name)
+		}
+		return parseLibraryBundle(name, []byte(content))
+	}
+
+	localPath := strings.TrimPrefix(source, "file://")
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadLibraryDir(localPath)
+	}
+
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseLibraryBundle(localPath, content)
+}
+
+// loadLibraryDir loads every template file below a directory, named by their
+// slash separated path relative to the directory without the extension
+func loadLibraryDir(dir string) ([]libraryTemplate, error) {
+	files, err := globFiles(filepath.ToSlash(dir) + "/**/*" + libraryTemplateExt)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]libraryTemplate, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, libraryTemplate{
+			Name:    libraryTemplateName(filepath.ToSlash(rel)),
+			Content: string(content),
+		})
+	}
+	return templates, nil
+}
+
+// parseLibraryBundle parses an archive or a single template file, using name to detect the format
+func parseLibraryBundle(name string, content []byte) ([]libraryTemplate, error) {
+	lower := strings.ToLower(name)
+	isTarGz := strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+	isZip := strings.HasSuffix(lower, ".zip")
+	if !isTarGz && !isZip {
+		return []libraryTemplate{{
+			Name:    libraryTemplateName(path.Base(filepath.ToSlash(name))),
+			Content: string(content),
+		}}, nil
+	}
+
+	f, err := loadFetcher()
+	if err != nil {
+		return nil, err
+	}
+	if isTarGz {
+		return readTarGzLibrary(content, f.maxBytes)
+	}
+	return readZipLibrary(content, f.maxBytes)
+}
+
+// archiveBudget bounds the total bytes extracted from an archive, so a small
+// compressed bundle cannot expand without limit
+type archiveBudget struct {
+	limit     int64
+	extracted int64
+}
+
+// read reads an archive entry, failing once the extracted total exceeds the limit
+func (b *archiveBudget) read(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, b.limit-b.extracted+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	b.extracted += int64(len(data))
+	if b.extracted > b.limit {
+		return nil, fmt.Errorf("prompt library archive exceeds fetch_max_bytes (%d bytes) when extracted", b.limit)
+	}
+	return data, nil
+}
+
+// readTarGzLibrary reads the template files of a gzip compressed tar archive,
+// extracting at most maxBytes in total
+func readTarGzLibrary(content []byte, maxBytes int64) ([]libraryTemplate, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tar.gz archive: %w", err)
+	}
+	defer gz.Close()
+
+	budget := &archiveBudget{limit: maxBytes}
+	var templates []libraryTemplate
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar.gz archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, libraryTemplateExt) {
+			continue
+		}
+
+		data, err := budget.read(tr, header.Name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, libraryTemplate{
+			Name:    libraryTemplateName(archiveEntryName(header.Name)),
+			Content: string(data),
+		})
+	}
+
+	sortLibraryTemplates(templates)
+	return templates, nil
+}
+
+// readZipLibrary reads the template files of a zip archive, extracting at most maxBytes in total
+func readZipLibrary(content []byte, maxBytes int64) ([]libraryTemplate, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	budget := &archiveBudget{limit: maxBytes}
+	var templates []libraryTemplate
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() || !strings.HasSuffix(file.Name, libraryTemplateExt) {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		data, err := budget.read(rc, file.Name)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, libraryTemplate{
+			Name:    libraryTemplateName(archiveEntryName(file.Name)),
+			Content: string(data),
+		})
+	}
+
+	sortLibraryTemplates(templates)
+	return templates, nil
+}
+
+// archiveEntryName normalizes an archive entry path, dropping a leading "./"
+func archiveEntryName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// libraryTemplateName derives a template name from a slash separated file path
+func libraryTemplateName(file string) string {
+	return strings.TrimSuffix(file, libraryTemplateExt)
+}
+
+// sortLibraryTemplates orders templates by name so archives parse deterministically
+func sortLibraryTemplates(templates []libraryTemplate) {
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+}
+
+// addLibraryTemplates parses library templates into the template set of tmpl
+func addLibraryTemplates(tmpl *template.Template, templates []libraryTemplate) error {
+	for _, lt := range templates {
+		if _, err := tmpl.New(lt.Name).Parse(lt.Content); err != nil {
+			return fmt.Errorf("failed to parse prompt_library template %s: %w", lt.Name, err)
+		}
+	}
+	return nil
+}
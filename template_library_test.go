@@ -0,0 +1,247 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// buildTarGz builds a gzip compressed tar archive from the files
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// buildZip builds a zip archive from the files
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPromptLibrary(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+
+	libDir := t.TempDir()
+	writeTestFiles(t, libDir, map[string]string{
+		"security-checklist.tmpl": "- check secrets in {{ .GITHUB_REPOSITORY }}",
+		"review/style.tmpl":       "- follow {{ .STYLE | default \"gofmt\" }}",
+		"blocks.tmpl":             `{{ define "tone" }}Be concise.{{ end }}`,
+		"notes.txt":               "not a template",
+	})
+
+	archiveDir := t.TempDir()
+	tarPath := filepath.Join(archiveDir, "library.tar.gz")
+	tarContent := buildTarGz(t, map[string]string{"./shared/header.tmpl": "Header for {{ .GITHUB_REPOSITORY }}"})
+	if err := os.WriteFile(tarPath, tarContent, 0o600); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	zipPath := filepath.Join(archiveDir, "library.zip")
+	if err := os.WriteFile(zipPath, buildZip(t, map[string]string{"footer.tmpl": "Footer", "README.md": "ignored"}), 0o600); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/bundle.tgz":
+			_, _ = w.Write(tarContent)
+		case "/persona.tmpl":
+			_, _ = w.Write([]byte(`{{ define "persona" }}You are a reviewer.{{ end }}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		library  string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "directory templates by file name",
+			library:  libDir,
+			template: `{{ template "security-checklist" . }}`,
+			want:     "- check secrets in owner/repo",
+		},
+		{
+			name:     "nested directory templates by relative path",
+			library:  libDir,
+			template: `{{ template "review/style" . }}`,
+			want:     "- follow gofmt",
+		},
+		{
+			name:     "define blocks from library files",
+			library:  libDir,
+			template: `{{ template "tone" }}`,
+			want:     "Be concise.",
+		},
+		{
+			name:     "prompt can redefine library blocks",
+			library:  libDir,
+			template: `{{ define "tone" }}Be thorough.{{ end }}{{ template "tone" }}`,
+			want:     "Be thorough.",
+		},
+		{
+			name:     "tar.gz archive",
+			library:  tarPath,
+			template: `{{ template "shared/header" . }}`,
+			want:     "Header for owner/repo",
+		},
+		{
+			name:     "zip archive",
+			library:  "file://" + zipPath,
+			template: `{{ template "footer" }}`,
+			want:     "Footer",
+		},
+		{
+			name:     "URL bundle",
+			library:  server.URL + "/bundle.tgz",
+			template: `{{ template "shared/header" . }}`,
+			want:     "Header for owner/repo",
+		},
+		{
+			name:     "multiple sources",
+			library:  libDir + "\n" + server.URL + "/persona.tmpl",
+			template: `{{ template "persona" }} {{ template "tone" }}`,
+			want:     "You are a reviewer. Be concise.",
+		},
+		{
+			name:     "missing template",
+			library:  libDir,
+			template: `{{ template "unknown" . }}`,
+			wantErr:  `template "unknown" not defined`,
+		},
+		{
+			name:     "missing source",
+			library:  filepath.Join(libDir, "missing"),
+			template: "hello",
+			wantErr:  "failed to load prompt_library",
+		},
+		{
+			name:     "URL not found",
+			library:  server.URL + "/missing.tmpl",
+			template: "hello",
+			wantErr:  "status code 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_PROMPT_LIBRARY", tt.library)
+
+			got, err := RenderTemplate(tt.template)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RenderTemplate() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The URL bundle is fetched once and then served from the cache
+	before := atomic.LoadInt32(&requests)
+	t.Setenv("INPUT_PROMPT_LIBRARY", server.URL+"/bundle.tgz")
+	if _, err := RenderTemplate(`{{ template "shared/header" . }}`); err != nil {
+		t.Fatalf("RenderTemplate() unexpected error: %v", err)
+	}
+	if after := atomic.LoadInt32(&requests); after != before {
+		t.Errorf("prompt library fetched %d more times, want cached", after-before)
+	}
+}
+
+func TestPromptLibraryWithIncludeTemplate(t *testing.T) {
+	setupWorkspace(t, map[string]string{
+		"partials/body.tmpl": `{{ template "greeting" }}`,
+	})
+
+	libDir := t.TempDir()
+	writeTestFiles(t, libDir, map[string]string{"greeting.tmpl": "Hello"})
+	t.Setenv("INPUT_PROMPT_LIBRARY", libDir)
+
+	got, err := RenderTemplate(`{{ includeTemplate "partials/body.tmpl" }}!`)
+	if err != nil {
+		t.Fatalf("RenderTemplate() unexpected error: %v", err)
+	}
+	if got != "Hello!" {
+		t.Errorf("RenderTemplate() = %q, want %q", got, "Hello!")
+	}
+}
+
+func TestPromptLibraryArchiveLimit(t *testing.T) {
+	files := map[string]string{
+		"a.tmpl": strings.Repeat("a", 600),
+		"b.tmpl": strings.Repeat("b", 600),
+	}
+	archives := map[string][]byte{
+		"library.tar.gz": buildTarGz(t, files),
+		"library.zip":    buildZip(t, files),
+	}
+
+	for name, content := range archives {
+		t.Run(name, func(t *testing.T) {
+			// Each entry fits the limit on its own, but not the two together
+			t.Setenv("INPUT_FETCH_MAX_BYTES", "1000")
+			_, err := parseLibraryBundle(name, content)
+			if err == nil || !strings.Contains(err.Error(), "exceeds fetch_max_bytes (1000 bytes) when extracted") {
+				t.Errorf("parseLibraryBundle() error = %v, want the extracted size limit", err)
+			}
+
+			t.Setenv("INPUT_FETCH_MAX_BYTES", "1200")
+			templates, err := parseLibraryBundle(name, content)
+			if err != nil || len(templates) != 2 {
+				t.Errorf("parseLibraryBundle() = %d templates, %v, want 2 templates", len(templates), err)
+			}
+		})
+	}
+}
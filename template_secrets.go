@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// defaultSecretEnvPatterns match environment variables that are never exposed to templates
+// unless explicitly allowlisted by exact name
+var defaultSecretEnvPatterns = []string{
+	"*API_KEY*",
+	"*APIKEY*",
+	"*TOKEN",
+	"TOKEN_*",
+	"*_TOKEN_*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*PRIVATE_KEY*",
+	"*CREDENTIAL*",
+	"*ACCESS_KEY*",
+	"*_AUTH",
+	// Custom headers often carry authentication
+	"INPUT_HEADERS",
+	"INPUT_FETCH_HEADERS",
+	"INPUT_OTEL_HEADERS",
+	"INPUT_METRICS_HEADERS",
+	"OTEL_EXPORTER_OTLP_*HEADERS",
+}
+
+// secretValuePatterns match well-known credential formats in rendered prompts
+var secretValuePatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}\b`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)},
+}
+
+// minSecretValueLen is the shortest secret env value searched for in prompts,
+// to avoid false positives on short values such as "true"
+const minSecretValueLen = 8
+
+// envFilter decides which environment variables are exposed to templates
+type envFilter struct {
+	allow []string
+	deny  []string
+}
+
+// loadEnvFilter builds the filter from the template_env_allowlist and
+// template_env_denylist inputs on top of the default secret patterns
+func loadEnvFilter() envFilter {
+	return envFilter{
+		allow: parsePatternList(os.Getenv("INPUT_TEMPLATE_ENV_ALLOWLIST")),
+		deny: append(
+			append([]string{}, defaultSecretEnvPatterns...),
+			parsePatternList(os.Getenv("INPUT_TEMPLATE_ENV_DENYLIST"))...,
+		),
+	}
+}
+
+// parsePatternList splits a comma or newline separated list of name patterns
+func parsePatternList(s string) []string {
+	var patterns []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, strings.ToUpper(p))
+		}
+	}
+	return patterns
+}
+
+// allowed reports whether an environment variable may be exposed to templates.
+// When an allowlist is set only matching names are exposed. Denied names stay hidden
+// unless the allowlist names them exactly, without wildcards.
+func (f envFilter) allowed(key string) bool {
+	name := strings.ToUpper(key)
+	if len(f.allow) > 0 && !matchesAnyPattern(name, f.allow) {
+		return false
+	}
+	if !matchesAnyPattern(name, f.deny) {
+		return true
+	}
+	for _, p := range f.allow {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnyPattern reports whether name matches one of the glob patterns
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// templateEnv implements the env template function, refusing variables hidden by the filter
+func templateEnv(name string) (string, error) {
+	if !loadEnvFilter().allowed(name) {
+		return "", fmt.Errorf("env: %s is not exposed to templates", name)
+	}
+	return os.Getenv(name), nil
+}
+
+// hiddenEnvValues returns the values of environment variables hidden from templates,
+// which must not appear in prompts either
+func hiddenEnvValues() []string {
+	filter := loadEnvFilter()
+	var values []string
+	for _, env := range os.Environ() {
+		key, value, found := strings.Cut(env, "=")
+		if !found || len(value) < minSecretValueLen || filter.allowed(key) {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// knownSecret is an exact secret value to look for in prompts
+type knownSecret struct {
+	name  string
+	value string
+}
+
+// findSecret returns a description of the first secret found in text, or an empty string.
+// Known values are matched exactly and everything else by common credential formats.
+func findSecret(text string, known []knownSecret) string {
+	for _, secret := range known {
+		if secret.value != "" && strings.Contains(text, secret.value) {
+			return secret.name
+		}
+	}
+	for _, p := range secretValuePatterns {
+		if p.re.MatchString(text) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// scanMessagesForSecrets refuses messages that contain the configured API keys,
+// values of hidden secret environment variables, or common credential formats
+func scanMessagesForSecrets(config *Config, messages []openai.ChatCompletionMessage) error {
+	if !config.SecretScan {
+		return nil
+	}
+
+	known := []knownSecret{
+		{"the configured api_key", config.APIKey},
+		{"the configured judge_api_key", config.JudgeAPIKey},
+	}
+	for _, value := range hiddenEnvValues() {
+		known = append(known, knownSecret{"the value of a secret environment variable", value})
+	}
+
+	for _, msg := range messages {
+		if secret := findSecret(msg.Content, known); secret != "" {
+			return fmt.Errorf(
+				"refusing to send %s message: it contains %s (set secret_scan: false to disable this check)",
+				msg.Role, secret,
+			)
+		}
+	}
+	return nil
+}
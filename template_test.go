@@ -31,13 +31,13 @@ func TestRenderTemplate(t *testing.T) {
 			wantErr:  false,
 		},
 		{
-			name:     "INPUT_ prefix removal",
+			name:     "INPUT_ prefix removal hides the API key",
 			template: "Model: {{.MODEL}}, API Key: {{.API_KEY}}",
 			envVars: map[string]string{
 				"INPUT_MODEL":   testModelGPT4,
 				"INPUT_API_KEY": "sk-xxx",
 			},
-			want:    "Model: gpt-4, API Key: sk-xxx",
+			want:    "Model: gpt-4, API Key: <no value>",
 			wantErr: false,
 		},
 		{
@@ -159,11 +159,12 @@ func TestBuildTemplateData(t *testing.T) {
 		t.Errorf("Expected MODEL (without prefix) to be %s, got %s", testModelGPT4, data["MODEL"])
 	}
 
-	if data["INPUT_API_KEY"] != "sk-test" {
-		t.Errorf("Expected INPUT_API_KEY to be sk-test, got %s", data["INPUT_API_KEY"])
+	// Secrets are never exposed to templates
+	if _, ok := data["INPUT_API_KEY"]; ok {
+		t.Errorf("Expected INPUT_API_KEY to be hidden, got %s", data["INPUT_API_KEY"])
 	}
-	if data["API_KEY"] != "sk-test" {
-		t.Errorf("Expected API_KEY (without prefix) to be sk-test, got %s", data["API_KEY"])
+	if _, ok := data["API_KEY"]; ok {
+		t.Errorf("Expected API_KEY (without prefix) to be hidden, got %s", data["API_KEY"])
 	}
 
 	// Check that non-INPUT_ variables are only available with original key
@@ -252,3 +253,77 @@ func TestRenderTemplateErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderTemplateStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		strict   bool
+		template string
+		want     string
+		wantErr  []string
+	}{
+		{
+			name:     "non-strict renders missing keys as no value",
+			template: "Repo: {{.GITHUB_REPOSITRY_TEST}}",
+			want:     "Repo: <no value>",
+		},
+		{
+			name:     "strict fails on missing keys with snippet",
+			strict:   true,
+			template: "Review:\nRepo: {{.GITHUB_REPOSITRY_TEST}}\nDone",
+			wantErr: []string{
+				`map has no entry for key "GITHUB_REPOSITRY_TEST"`,
+				"    2 | Repo: {{.GITHUB_REPOSITRY_TEST}}",
+				"      |         ^",
+			},
+		},
+		{
+			name:     "strict fails on missing nested keys",
+			strict:   true,
+			template: "{{.vars.missing}}",
+			wantErr:  []string{`map has no entry for key "missing"`},
+		},
+		{
+			name:     "strict allows index with default",
+			strict:   true,
+			template: `{{ index . "GITHUB_REPOSITRY_TEST" | default "none" }}`,
+			want:     "none",
+		},
+		{
+			name:     "strict renders existing keys",
+			strict:   true,
+			template: "{{.vars.team}}",
+			want:     "platform",
+		},
+		{
+			name:     "parse errors show the line",
+			template: "line one\n{{ if .X }}\nline three {{ end",
+			wantErr:  []string{"failed to parse template", "    3 | line three {{ end"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_VARS", "team: platform")
+
+			got, err := RenderTemplateWithData(tt.template, nil, tt.strict)
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("RenderTemplateWithData() expected error, got %q", got)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("RenderTemplateWithData() error = %q, want it to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplateWithData() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplateWithData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// templateVarsCache holds parsed vars keyed by the vars input
+var templateVarsCache loadCache[map[string]any]
+
+// loadTemplateVars loads the vars input, which can be text, a file path or a URL, and
+// parses it as a YAML or JSON map, or as key=value lines. The result is exposed to
+// templates as {{.vars}}.
+func loadTemplateVars(input string) (map[string]any, error) {
+	if strings.TrimSpace(input) == "" {
+		return map[string]any{}, nil
+	}
+
+	return templateVarsCache.get(input, func(input string) (map[string]any, error) {
+		content, err := LoadContent(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vars: %w", err)
+		}
+
+		vars, err := parseTemplateVars(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vars value: %w", err)
+		}
+		return vars, nil
+	})
+}
+
+// parseTemplateVars parses a YAML or JSON map, falling back to key=value lines
+func parseTemplateVars(content string) (map[string]any, error) {
+	if strings.TrimSpace(content) == "" {
+		return map[string]any{}, nil
+	}
+
+	// JSON is valid YAML, so a single decoder handles both formats
+	var vars map[string]any
+	yamlErr := yaml.Unmarshal([]byte(content), &vars)
+	if yamlErr == nil && vars != nil {
+		return vars, nil
+	}
+
+	vars, ok := parseKeyValueLines(content)
+	if !ok {
+		if yamlErr != nil {
+			return nil, fmt.Errorf("expected a YAML/JSON map or key=value lines: %w", yamlErr)
+		}
+		return nil, fmt.Errorf("expected a YAML/JSON map or key=value lines")
+	}
+	return vars, nil
+}
+
+// parseKeyValueLines parses key=value lines, ignoring blank lines and # comments.
+// It reports false when any other line is not a key=value pair.
+func parseKeyValueLines(content string) (map[string]any, bool) {
+	vars := make(map[string]any)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, false
+		}
+		vars[key] = strings.TrimSpace(value)
+	}
+	return vars, true
+}
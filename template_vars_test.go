@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateVars(t *testing.T) {
+	varsFile := filepath.Join(t.TempDir(), "vars.yml")
+	if err := os.WriteFile(varsFile, []byte("team: platform\nowners:\n  - alice\n  - bob\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"language": "Go"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		vars     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "YAML map",
+			vars:     "language: Go\nstrict: true",
+			template: "{{ .vars.language }} {{ .vars.strict }}",
+			want:     "Go true",
+		},
+		{
+			name:     "nested YAML",
+			vars:     "review:\n  focus:\n    - security\n    - performance\n  depth: 2",
+			template: `{{ range .vars.review.focus }}[{{ . }}]{{ end }} depth={{ .vars.review.depth }}`,
+			want:     "[security][performance] depth=2",
+		},
+		{
+			name:     "JSON map",
+			vars:     `{"reviewers": [{"name": "alice"}, {"name": "bob"}]}`,
+			template: `{{ range .vars.reviewers }}{{ .name }};{{ end }}`,
+			want:     "alice;bob;",
+		},
+		{
+			name:     "key=value lines",
+			vars:     "# comment\nlanguage=Go\n\nurl = https://example.com/?a=b\n",
+			template: "{{ .vars.language }} {{ .vars.url }}",
+			want:     "Go https://example.com/?a=b",
+		},
+		{
+			name:     "single key=value line",
+			vars:     "language=Go",
+			template: "{{ .vars.language }}",
+			want:     "Go",
+		},
+		{
+			name:     "vars from file",
+			vars:     varsFile,
+			template: `{{ .vars.team }}: {{ .vars.owners | join ", " }}`,
+			want:     "platform: alice, bob",
+		},
+		{
+			name:     "vars from URL",
+			vars:     server.URL,
+			template: "{{ .vars.language }}",
+			want:     "Go",
+		},
+		{
+			name:     "no vars",
+			template: "{{ .vars.missing | default \"none\" }}",
+			want:     "none",
+		},
+		{
+			name:     "invalid vars",
+			vars:     "just some text",
+			template: "{{ .vars.language }}",
+			wantErr:  "invalid vars value",
+		},
+		{
+			name:     "missing file",
+			vars:     "file://" + filepath.Join(t.TempDir(), "missing.yml"),
+			template: "{{ .vars.language }}",
+			wantErr:  "failed to load vars",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_VARS", tt.vars)
+
+			got, err := RenderTemplate(tt.template)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RenderTemplate() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// Limits for files read by templates, overridable with INPUT_TEMPLATE_MAX_FILE_BYTES
+// and INPUT_TEMPLATE_MAX_TOTAL_BYTES
+const (
+	defaultTemplateMaxFileBytes  = 256 * 1024
+	defaultTemplateMaxTotalBytes = 1024 * 1024
+	// maxIncludeDepth bounds nested includeTemplate calls to catch include cycles
+	maxIncludeDepth = 10
+	// binarySniffLen is how many leading bytes are inspected for binary detection
+	binarySniffLen = 8000
+)
+
+// workspaceReader gives templates read-only access to files inside the workspace.
+// One reader is used per render so the total byte limit covers a whole prompt.
+type workspaceReader struct {
+	root          string
+	maxFileBytes  int64
+	maxTotalBytes int64
+	totalBytes    int64
+	depth         int
+}
+
+// newWorkspaceReader creates a reader confined to GITHUB_WORKSPACE,
+// falling back to the current directory when it is not set
+func newWorkspaceReader() (*workspaceReader, error) {
+	root := os.Getenv("GITHUB_WORKSPACE")
+	if root == "" {
+		root = "."
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	// Resolve symlinks so containment checks compare real paths
+	if resolved, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = resolved
+	}
+
+	w := &workspaceReader{
+		root:          absRoot,
+		maxFileBytes:  defaultTemplateMaxFileBytes,
+		maxTotalBytes: defaultTemplateMaxTotalBytes,
+	}
+
+	if w.maxFileBytes, err = parseByteLimit(
+		"template_max_file_bytes", os.Getenv("INPUT_TEMPLATE_MAX_FILE_BYTES"), w.maxFileBytes,
+	); err != nil {
+		return nil, err
+	}
+	if w.maxTotalBytes, err = parseByteLimit(
+		"template_max_total_bytes", os.Getenv("INPUT_TEMPLATE_MAX_TOTAL_BYTES"), w.maxTotalBytes,
+	); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// parseByteLimit parses a positive byte count, returning def for an empty string
+func parseByteLimit(name, s string, def int64) (int64, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value: %w", name, err)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return n, nil
+}
+
+// funcs returns the workspace template functions bound to this reader,
+// rendering included templates with render
+func (w *workspaceReader) funcs(render func(name, text string) (string, error)) template.FuncMap {
+	return template.FuncMap{
+		"readFile": w.readFile,
+		"glob":     w.glob,
+		"includeTemplate": func(path string) (string, error) {
+			return w.includeTemplate(path, render)
+		},
+	}
+}
+
+// resolve returns the absolute path of a workspace relative path, rejecting any
+// path that escapes the workspace directly or through symlinks
+func (w *workspaceReader) resolve(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("empty path")
+	}
+
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(w.root, path)
+	}
+	full = filepath.Clean(full)
+	if !w.contains(full) {
+		return "", fmt.Errorf("path %q is outside the workspace", path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q: %w", path, err)
+	}
+	if !w.contains(resolved) {
+		return "", fmt.Errorf("path %q is outside the workspace", path)
+	}
+
+	return resolved, nil
+}
+
+// contains reports whether an absolute, clean path is inside the workspace root
+func (w *workspaceReader) contains(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// readFile returns the content of a text file inside the workspace
+func (w *workspaceReader) readFile(path string) (string, error) {
+	full, err := w.resolve(path)
+	if err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+
+	// Refuse FIFOs and devices before opening them, since opening a FIFO blocks
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("readFile: %q is not a regular file", path)
+	}
+	if info.Size() > w.maxFileBytes {
+		return "", fmt.Errorf(
+			"readFile: %q is %d bytes, exceeding the per-file limit of %d bytes",
+			path, info.Size(), w.maxFileBytes,
+		)
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+	defer f.Close()
+	// The path may have been replaced since it was checked
+	if info, err = f.Stat(); err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("readFile: %q is not a regular file", path)
+	}
+
+	// The file can still grow, so the limits are enforced while reading, one byte past
+	// the remaining budget to detect oversized files
+	limit := min(w.maxFileBytes, w.maxTotalBytes-w.totalBytes)
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("readFile: %w", err)
+	}
+	if int64(len(content)) > w.maxFileBytes {
+		return "", fmt.Errorf("readFile: %q exceeds the per-file limit of %d bytes", path, w.maxFileBytes)
+	}
+	if w.totalBytes+int64(len(content)) > w.maxTotalBytes {
+		return "", fmt.Errorf(
+			"readFile: reading %q would exceed the total limit of %d bytes",
+			path, w.maxTotalBytes,
+		)
+	}
+	if isBinary(content) {
+		return "", fmt.Errorf("readFile: %q is a binary file", path)
+	}
+
+	w.totalBytes += int64(len(content))
+	return string(content), nil
+}
+
+// glob returns workspace relative, slash separated paths of files matching the pattern
+func (w *workspaceReader) glob(pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	if filepath.IsAbs(pattern) || strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("glob: pattern %q must be relative to the workspace", pattern)
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ".." {
+			return nil, fmt.Errorf("glob: pattern %q must not contain '..'", pattern)
+		}
+	}
+
+	matches, err := globFiles(filepath.ToSlash(w.root) + "/" + strings.TrimPrefix(pattern, "./"))
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		rel, err := filepath.Rel(w.root, match)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths, nil
+}
+
+// includeTemplate renders another workspace file as a template with the same data
+func (w *workspaceReader) includeTemplate(path string, render func(name, text string) (string, error)) (string, error) {
+	if w.depth >= maxIncludeDepth {
+		return "", fmt.Errorf("includeTemplate: maximum include depth of %d exceeded at %q", maxIncludeDepth, path)
+	}
+
+	content, err := w.readFile(path)
+	if err != nil {
+		return "", fmt.Errorf("includeTemplate: %w", err)
+	}
+
+	w.depth++
+	defer func() { w.depth-- }()
+	return render(path, content)
+}
+
+// isBinary reports whether content looks like a binary file: it contains a NUL byte
+// in its first bytes or is not valid UTF-8
+func isBinary(content []byte) bool {
+	sniff := content
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+	}
+	return bytes.IndexByte(sniff, 0) != -1 || !utf8.Valid(content)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFiles writes files below dir, creating parent directories as needed
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+}
+
+// setupWorkspace creates a workspace with the given files and points GITHUB_WORKSPACE at it
+func setupWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	writeTestFiles(t, root, files)
+	t.Setenv("GITHUB_WORKSPACE", root)
+	return root
+}
+
+func TestWorkspaceTemplateFuncs(t *testing.T) {
+	setupWorkspace(t, map[string]string{
+		"README.md":                "# Project",
+		"src/main.go":              "package main",
+		"src/util/strings.go":      "package util",
+		"src/util/strings_test.go": "package util",
+		"partials/header.tmpl":     `Repo: {{ .GITHUB_REPOSITORY }}{{ includeTemplate "partials/footer.tmpl" }}`,
+		"partials/footer.tmpl":     ` ({{ readFile "README.md" }})`,
+		"partials/loop.tmpl":       `{{ includeTemplate "partials/loop.tmpl" }}`,
+		"image.png":                "\x89PNG\r\n\x1a\n\x00\x00",
+	})
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "readFile",
+			template: `{{ readFile "README.md" }}`,
+			want:     "# Project",
+		},
+		{
+			name:     "readFile with dot prefix",
+			template: `{{ readFile "./src/main.go" }}`,
+			want:     "package main",
+		},
+		{
+			name:     "glob with double star",
+			template: `{{ glob "src/**/*.go" | join "," }}`,
+			want:     "src/main.go,src/util/strings.go,src/util/strings_test.go",
+		},
+		{
+			name:     "glob and readFile",
+			template: `{{ range glob "src/util/*_test.go" }}{{ . }}={{ readFile . }}{{ end }}`,
+			want:     "src/util/strings_test.go=package util",
+		},
+		{
+			name:     "glob without matches",
+			template: `{{ glob "docs/*.md" | len }}`,
+			want:     "0",
+		},
+		{
+			name:     "includeTemplate renders with data",
+			template: `{{ includeTemplate "partials/header.tmpl" }}`,
+			want:     "Repo: owner/repo (# Project)",
+		},
+		{
+			name:     "readFile rejects traversal",
+			template: `{{ readFile "../secret.txt" }}`,
+			wantErr:  "outside the workspace",
+		},
+		{
+			name:     "readFile rejects absolute path outside workspace",
+			template: `{{ readFile "` + filepath.ToSlash(outside) + `" }}`,
+			wantErr:  "outside the workspace",
+		},
+		{
+			name:     "readFile rejects binary files",
+			template: `{{ readFile "image.png" }}`,
+			wantErr:  "binary file",
+		},
+		{
+			name:     "readFile rejects directories",
+			template: `{{ readFile "src" }}`,
+			wantErr:  "not a regular file",
+		},
+		{
+			name:     "readFile missing file",
+			template: `{{ readFile "missing.txt" }}`,
+			wantErr:  "failed to resolve",
+		},
+		{
+			name:     "glob rejects parent segments",
+			template: `{{ glob "../**/*.txt" }}`,
+			wantErr:  "must not contain '..'",
+		},
+		{
+			name:     "glob rejects absolute patterns",
+			template: `{{ glob "/etc/*" }}`,
+			wantErr:  "must be relative",
+		},
+		{
+			name:     "includeTemplate stops cycles",
+			template: `{{ includeTemplate "partials/loop.tmpl" }}`,
+			wantErr:  "maximum include depth",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.template)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RenderTemplate() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplate() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceSymlinkEscape(t *testing.T) {
+	root := setupWorkspace(t, map[string]string{"inside.txt": "inside"})
+
+	outsideDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	_, err := RenderTemplate(`{{ readFile "link/secret.txt" }}`)
+	if err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+		t.Fatalf("RenderTemplate() error = %v, want symlink escape to be rejected", err)
+	}
+}
+
+func TestWorkspaceByteLimits(t *testing.T) {
+	setupWorkspace(t, map[string]string{
+		"a.txt": strings.Repeat("a", 60),
+		"b.txt": strings.Repeat("b", 60),
+	})
+
+	tests := []struct {
+		name      string
+		fileLimit string
+		total     string
+		template  string
+		wantErr   string
+	}{
+		{
+			name:     "within limits",
+			total:    "120",
+			template: `{{ readFile "a.txt" }}{{ readFile "b.txt" }}`,
+		},
+		{
+			name:      "per-file limit",
+			fileLimit: "50",
+			template:  `{{ readFile "a.txt" }}`,
+			wantErr:   "exceeding the per-file limit of 50 bytes",
+		},
+		{
+			name:     "total limit",
+			total:    "100",
+			template: `{{ readFile "a.txt" }}{{ readFile "b.txt" }}`,
+			wantErr:  "total limit of 100 bytes",
+		},
+		{
+			name:      "invalid limit",
+			fileLimit: "lots",
+			template:  `{{ readFile "a.txt" }}`,
+			wantErr:   "invalid template_max_file_bytes value",
+		},
+		{
+			name:     "non-positive limit",
+			total:    "0",
+			template: `{{ readFile "a.txt" }}`,
+			wantErr:  "template_max_total_bytes must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INPUT_TEMPLATE_MAX_FILE_BYTES", tt.fileLimit)
+			t.Setenv("INPUT_TEMPLATE_MAX_TOTAL_BYTES", tt.total)
+
+			_, err := RenderTemplate(tt.template)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("RenderTemplate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("RenderTemplate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
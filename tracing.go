@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Span kinds of the OTLP data model
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// Span status codes of the OTLP data model
+const (
+	spanStatusOK    = 1
+	spanStatusError = 2
+)
+
+const (
+	defaultTraceServiceName = "llm-action"
+	tracesPath              = "/v1/traces"
+	traceExportTimeout      = 10 * time.Second
+)
+
+// activeTracer records the spans of the current run, or is nil when tracing is disabled
+var activeTracer *tracer
+
+// spanContextKey stores the current span in a context
+type spanContextKey struct{}
+
+// tracer collects the spans of a run and exports them as OTLP/JSON to a collector
+// endpoint or a file when the run finishes
+type tracer struct {
+	mu          sync.Mutex
+	traceID     string
+	serviceName string
+	endpoint    string
+	headers     map[string]string
+	file        string
+	spans       []*span
+	// stack holds the open spans started without a span in their context, so
+	// sequential steps such as config loading nest without passing a context
+	stack []*span
+}
+
+// span is a timed operation of a trace
+type span struct {
+	tracer     *tracer
+	spanID     string
+	parentID   string
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	status     int
+	message    string
+}
+
+// newTracer creates a tracer from the otel_* inputs, falling back to the standard
+// OTEL_* environment variables. It returns nil when no exporter is configured.
+func newTracer() (*tracer, error) {
+	endpoint := firstEnv("INPUT_OTEL_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	file := strings.TrimSpace(os.Getenv("INPUT_OTEL_FILE"))
+	if endpoint == "" && file == "" {
+		return nil, nil
+	}
+
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid otel_endpoint value: %q (expected an http or https URL)", endpoint)
+		}
+		if !strings.HasSuffix(u.Path, tracesPath) {
+			u.Path = strings.TrimSuffix(u.Path, "/") + tracesPath
+		}
+		endpoint = u.String()
+	}
+
+	headers, err := parseHeaderList(os.Getenv("INPUT_OTEL_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otel_headers value: %w", err)
+	}
+
+	serviceName := firstEnv("INPUT_OTEL_SERVICE_NAME", "OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultTraceServiceName
+	}
+
+	return &tracer{
+		traceID:     randomHex(16),
+		serviceName: serviceName,
+		endpoint:    endpoint,
+		headers:     headers,
+		file:        file,
+	}, nil
+}
+
+// startTracing enables tracing when an exporter is configured and starts the root span
+// of the run. The returned function ends the root span and exports the trace.
+func startTracing(ctx context.Context) (context.Context, func(error), error) {
+	t, err := newTracer()
+	if err != nil || t == nil {
+		return ctx, func(error) {}, err
+	}
+
+	activeTracer = t
+	ctx, root := startSpan(ctx, "run", spanKindInternal)
+	return ctx, func(err error) {
+		root.finish(err)
+		activeTracer = nil
+		if err := t.export(); err != nil {
+			warnf("%v", err)
+		}
+	}, nil
+}
+
+// firstEnv returns the first non-empty environment variable of the names
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// randomHex returns n random bytes as lowercase hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startSpan starts a span named name as a child of the span in ctx. Without a span in
+// ctx, the span nests under the innermost open span started the same way. It returns
+// ctx unchanged and a nil span when tracing is disabled.
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *span) {
+	t := activeTracer
+	if t == nil {
+		return ctx, nil
+	}
+
+	s := &span{
+		tracer:     t,
+		spanID:     randomHex(8),
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]any),
+	}
+
+	t.mu.Lock()
+	if parent, ok := ctx.Value(spanContextKey{}).(*span); ok {
+		s.parentID = parent.spanID
+	} else {
+		if len(t.stack) > 0 {
+			s.parentID = t.stack[len(t.stack)-1].spanID
+		}
+		t.stack = append(t.stack, s)
+	}
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// setAttribute sets a string, bool, int, float64 or []string attribute
+func (s *span) setAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	s.attributes[key] = value
+	s.tracer.mu.Unlock()
+}
+
+// finish ends the span, marking it as failed when err is set
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s.end = time.Now()
+	s.status = spanStatusOK
+	if err != nil {
+		s.status = spanStatusError
+		s.message = err.Error()
+	}
+	for i := len(t.stack) - 1; i >= 0; i-- {
+		if t.stack[i] == s {
+			t.stack = append(t.stack[:i], t.stack[i+1:]...)
+			break
+		}
+	}
+}
+
+// traceparent returns the W3C trace context header value of the span
+func (s *span) traceparent() string {
+	return "00-" + s.tracer.traceID + "-" + s.spanID + "-01"
+}
+
+// traceLoader wraps the loader of a prompt input in a span
+func traceLoader(input, kind string, load func(string) (string, error)) func(string) (string, error) {
+	return func(s string) (string, error) {
+		_, span := startSpan(context.Background(), "load_prompt "+input, spanKindInternal)
+		span.setAttribute("llm_action.input", input)
+		span.setAttribute("llm_action.prompt.source", kind)
+
+		content, err := load(s)
+		span.setAttribute("llm_action.prompt.bytes", len(content))
+		span.finish(err)
+		return content, err
+	}
+}
+
+// tracedChatCompletion sends a chat completion request in a GenAI chat span. The kind
+// tells regular completions apart from judge calls.
+func tracedChatCompletion(
+	ctx context.Context,
+	client *openai.Client,
+	config *Config,
+	req openai.ChatCompletionRequest,
+	kind string,
+) (openai.ChatCompletionResponse, error) {
+	spanCtx, span := startSpan(ctx, "chat "+req.Model, spanKindClient)
+	span.setAttribute("llm_action.completion.kind", kind)
+	resp, err := client.CreateChatCompletion(spanCtx, req)
+	span.setChatAttributes(config, req, resp)
+	span.finish(err)
+	return resp, err
+}
+
+// setChatAttributes annotates a completion span with GenAI semantic convention attributes
+func (s *span) setChatAttributes(
+	config *Config,
+	req openai.ChatCompletionRequest,
+	resp openai.ChatCompletionResponse,
+) {
+	if s == nil {
+		return
+	}
+
+	s.setAttribute("gen_ai.operation.name", "chat")
+	s.setAttribute("gen_ai.system", "openai")
+	s.setAttribute("gen_ai.request.model", req.Model)
+	s.setAttribute("gen_ai.request.temperature", float64(req.Temperature))
+	s.setAttribute("gen_ai.request.max_tokens", req.MaxTokens)
+	if req.N > 1 {
+		s.setAttribute("gen_ai.request.choice.count", req.N)
+	}
+	if u, err := url.Parse(config.BaseURL); err == nil {
+		s.setAttribute("server.address", u.Hostname())
+	}
+
+	if len(resp.Choices) == 0 {
+		return
+	}
+	if resp.ID != "" {
+		s.setAttribute("gen_ai.response.id", resp.ID)
+	}
+	if resp.Model != "" {
+		s.setAttribute("gen_ai.response.model", resp.Model)
+	}
+	reasons := make([]string, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		reasons = append(reasons, string(choice.FinishReason))
+	}
+	s.setAttribute("gen_ai.response.finish_reasons", reasons)
+	s.setAttribute("gen_ai.usage.input_tokens", resp.Usage.PromptTokens)
+	s.setAttribute("gen_ai.usage.output_tokens", resp.Usage.CompletionTokens)
+	s.setAttribute("llm_action.latency_ms", time.Since(s.start).Milliseconds())
+}
+
+// tracingTransport records a client span for every HTTP attempt and propagates the
+// trace context to the server
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := startSpan(req.Context(), req.Method, spanKindClient)
+	if span == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	span.setAttribute("http.request.method", req.Method)
+	span.setAttribute("url.full", redactURL(req.URL))
+	span.setAttribute("server.address", req.URL.Hostname())
+	if port := req.URL.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err == nil {
+			span.setAttribute("server.port", n)
+		}
+	}
+
+	reqClone := req.Clone(ctx)
+	reqClone.Header.Set("traceparent", span.traceparent())
+	resp, err := t.base.RoundTrip(reqClone)
+	if err != nil {
+		span.setAttribute("error.type", fmt.Sprintf("%T", err))
+		span.finish(err)
+		return nil, err
+	}
+
+	span.setAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.setAttribute("error.type", strconv.Itoa(resp.StatusCode))
+		span.finish(fmt.Errorf("%s", resp.Status))
+		return resp, nil
+	}
+	span.finish(nil)
+	return resp, nil
+}
+
+// redactURL drops credentials and the query, which may carry tokens, from a URL
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	clean.Fragment = ""
+	return clean.String()
+}
+
+// export sends the recorded spans to the configured endpoint and appends them to the
+// configured file as a single OTLP/JSON line
+func (t *tracer) export() error {
+	data, err := json.Marshal(t.payload())
+	if err != nil {
+		return fmt.Errorf("failed to marshal traces: %w", err)
+	}
+
+	if t.file != "" {
+		f, err := os.OpenFile(t.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open otel_file: %w", err)
+		}
+		_, err = f.Write(append(data, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write otel_file: %w", err)
+		}
+	}
+
+	if t.endpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create trace export request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", GetUserAgent())
+		for key, value := range t.headers {
+			req.Header.Set(key, value)
+		}
+
+		// The default client keeps the export out of the trace
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to export traces: %w", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("failed to export traces: %s", resp.Status)
+		}
+	}
+
+	return nil
+}
+
+// payload builds an OTLP ExportTraceServiceRequest in its JSON encoding
+func (t *tracer) payload() map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resource := map[string]any{
+		"service.name":    t.serviceName,
+		"service.version": Version,
+	}
+	for attr, env := range map[string]string{
+		"github.repository": "GITHUB_REPOSITORY",
+		"github.workflow":   "GITHUB_WORKFLOW",
+		"github.run_id":     "GITHUB_RUN_ID",
+		"github.ref":        "GITHUB_REF",
+		"github.sha":        "GITHUB_SHA",
+	} {
+		if v := os.Getenv(env); v != "" {
+			resource[attr] = v
+		}
+	}
+
+	spans := make([]map[string]any, 0, len(t.spans))
+	for _, s := range t.spans {
+		end := s.end
+		if end.IsZero() {
+			end = time.Now()
+		}
+		item := map[string]any{
+			"traceId":           t.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attributes),
+			"status":            map[string]any{"code": s.status, "message": s.message},
+		}
+		if s.parentID != "" {
+			item["parentSpanId"] = s.parentID
+		}
+		spans = append(spans, item)
+	}
+
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": otlpAttributes(resource)},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": ActionName, "version": Version},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// otlpAttributes converts attributes to OTLP key-value pairs sorted by key
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	list := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, map[string]any{"key": key, "value": otlpValue(attributes[key])})
+	}
+	return list
+}
+
+// otlpValue converts a value to an OTLP AnyValue. Integers are strings in OTLP/JSON.
+func otlpValue(value any) map[string]any {
+	switch v := value.(type) {
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case []string:
+		values := make([]map[string]any, 0, len(v))
+		for _, s := range v {
+			values = append(values, otlpValue(s))
+		}
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}